@@ -0,0 +1,47 @@
+package satfetch
+
+// Boxscore is one country's row of Space Track's boxscore class: how many
+// of its objects are on orbit and how many have decayed, by type. Counts are
+// kept as Space Track sends them.
+type Boxscore struct {
+	Country         string `json:"country"`
+	SpadocCode      string `json:"spadocCode"`
+	OrbitalTBA      string `json:"orbitalTBA"`
+	OrbitalPayloads string `json:"orbitalPayloads"`
+	OrbitalRockets  string `json:"orbitalRocketBodies"`
+	OrbitalDebris   string `json:"orbitalDebris"`
+	OrbitalTotal    string `json:"orbitalTotal"`
+	DecayedPayloads string `json:"decayedPayloads"`
+	DecayedRockets  string `json:"decayedRocketBodies"`
+	DecayedDebris   string `json:"decayedDebris"`
+	DecayedTotal    string `json:"decayedTotal"`
+	CountryTotal    string `json:"countryTotal"`
+}
+
+// FetchBoxscore queries Space Track for the boxscore, in the order it gives
+// the countries.
+func (c *Client) FetchBoxscore() ([]Boxscore, error) {
+	var records []struct {
+		Country         string `json:"COUNTRY"`
+		SpadocCode      string `json:"SPADOC_CD"`
+		OrbitalTBA      string `json:"ORBITAL_TBA"`
+		OrbitalPayloads string `json:"ORBITAL_PAYLOAD_COUNT"`
+		OrbitalRockets  string `json:"ORBITAL_ROCKET_BODY_COUNT"`
+		OrbitalDebris   string `json:"ORBITAL_DEBRIS_COUNT"`
+		OrbitalTotal    string `json:"ORBITAL_TOTAL_COUNT"`
+		DecayedPayloads string `json:"DECAYED_PAYLOAD_COUNT"`
+		DecayedRockets  string `json:"DECAYED_ROCKET_BODY_COUNT"`
+		DecayedDebris   string `json:"DECAYED_DEBRIS_COUNT"`
+		DecayedTotal    string `json:"DECAYED_TOTAL_COUNT"`
+		CountryTotal    string `json:"COUNTRY_TOTAL"`
+	}
+	if err := c.queryRecords("boxscore", &records); err != nil {
+		return nil, err
+	}
+
+	boxscore := make([]Boxscore, len(records))
+	for i, r := range records {
+		boxscore[i] = Boxscore(r)
+	}
+	return boxscore, nil
+}
@@ -0,0 +1,60 @@
+package satfetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheKey returns the name of the file holding the response to queryURL,
+// in a cache or fixture directory.
+func CacheKey(queryURL string) string {
+	sum := sha256.Sum256([]byte(queryURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheFilename returns the file in the client's CacheDir holding the
+// response to queryURL.
+func (c *Client) cacheFilename(queryURL string) string {
+	return filepath.Join(c.CacheDir, CacheKey(queryURL))
+}
+
+// readCache returns the cached response to queryURL, if the client has a
+// CacheDir holding one no older than CacheTTL.
+func (c *Client) readCache(queryURL string) ([]byte, bool) {
+	if c.CacheDir == "" {
+		return nil, false
+	}
+
+	filename := c.cacheFilename(queryURL)
+	info, err := os.Stat(filename)
+	if err != nil || c.CacheTTL > 0 && time.Since(info.ModTime()) > c.CacheTTL {
+		return nil, false
+	}
+	body, err := os.ReadFile(filename)
+	if err != nil {
+		slog.Warn("Reading cached response failed", "err", err)
+		return nil, false
+	}
+	slog.Debug("Using cached response", "url", queryURL, "file", filename)
+	return body, true
+}
+
+// writeCache saves body as the response to queryURL, if the client has a
+// CacheDir. Failing to is only worth a warning.
+func (c *Client) writeCache(queryURL string, body []byte) {
+	if c.CacheDir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
+		slog.Warn("Caching response failed", "err", err)
+		return
+	}
+	if err := WriteFile(c.cacheFilename(queryURL), body, 0644); err != nil {
+		slog.Warn("Caching response failed", "err", err)
+	}
+}
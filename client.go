@@ -0,0 +1,543 @@
+package satfetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAuthFailed is returned when Space Track rejects the client's
+// credentials or session.
+var ErrAuthFailed = errors.New("space track authentication failed")
+
+// ErrBudgetExhausted is returned by queries once a Client has made
+// MaxRequests of them.
+var ErrBudgetExhausted = errors.New("space track request budget exhausted")
+
+// DefaultMaxAttempts is how many times a Client tries a query by default.
+const DefaultMaxAttempts = 4
+
+// Doer sends HTTP requests. *http.Client is one; tests can substitute a stub
+// or a client of an httptest.Server.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client is a Space Track session. It logs in once and reuses the session
+// cookie for subsequent queries instead of sending credentials every time.
+type Client struct {
+	LoginURL string // e.g. https://www.space-track.org/ajaxauth/login
+	APIRoot  string // e.g. https://www.space-track.org/basicspacedata
+	Identity string
+	Password string
+
+	// MaxAttempts is how many times a query is tried before giving up on
+	// transient failures.
+	MaxAttempts int
+
+	// MaxRequests, if positive, caps how many query requests the client
+	// makes in all, retries included.
+	MaxRequests int
+	requests    atomic.Int64
+
+	// CacheDir, if set, is where query responses are kept, named for a hash
+	// of the query URL, so repeated queries needn't reach Space Track. Ones
+	// older than CacheTTL, if positive, are fetched again.
+	CacheDir string
+	CacheTTL time.Duration
+
+	// HTTPClient sends the client's requests. The session cookie only
+	// persists if it keeps a cookie jar, as the default *http.Client does.
+	HTTPClient Doer
+
+	// TLEClass is the Space Track class element sets are read from: gp, the
+	// default, or gp_history, which FetchTLE reads the current element sets
+	// of from gp. Set it to the deprecated tle to read tle_latest and tle
+	// instead.
+	TLEClass string
+
+	limiter *rateLimiter
+	stats   *clientStats
+
+	// accounts, if any, are sessions of their own, each with its own cookie
+	// jar and rate limits, that queries are spread over in turn instead of
+	// being made with the client's credentials. Set by WithAccounts.
+	accounts    []*Client
+	credentials []Account
+	nextAccount atomic.Uint64
+	disabled    atomic.Bool // left out of the accounts in turn
+}
+
+// ClientOption configures a Client made by NewClient.
+type ClientOption func(*Client)
+
+// WithTransport has the client's default *http.Client send requests through
+// t instead of http.DefaultTransport, for control over dialing, name
+// resolution, TLS and timeouts. For example, to only connect over IPv6:
+//
+//	dialer := &net.Dialer{Timeout: 30 * time.Second}
+//	transport := http.DefaultTransport.(*http.Transport).Clone()
+//	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+//		return dialer.DialContext(ctx, "tcp6", addr)
+//	}
+//	client := NewClient(loginURL, apiRoot, identity, password, WithTransport(transport))
+//
+// A net.Dialer with a Resolver of its own pins the DNS server the same way.
+func WithTransport(t http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		if httpClient, ok := c.HTTPClient.(*http.Client); ok {
+			httpClient.Transport = t
+		}
+	}
+}
+
+// WithAccounts has the client spread its queries over the accounts in turn,
+// each logged in with a cookie jar and rate limits of its own, so that
+// together they can make more requests than one could. The client's own
+// identity and password go unused. An account whose credentials are
+// rejected is left out from then on.
+func WithAccounts(accounts ...Account) ClientOption {
+	return func(c *Client) {
+		c.credentials = append(c.credentials, accounts...)
+	}
+}
+
+// NewClient returns a Client with an empty cookie jar, limited to Space
+// Track's default request rates, with opts applied. Call Login before
+// issuing queries.
+func NewClient(loginURL string, apiRoot string, identity string, password string, opts ...ClientOption) *Client {
+	// cookiejar.New only fails when given options with a bad PublicSuffixList.
+	jar, _ := cookiejar.New(nil)
+
+	c := &Client{
+		LoginURL:    loginURL,
+		APIRoot:     apiRoot,
+		Identity:    identity,
+		Password:    password,
+		MaxAttempts: DefaultMaxAttempts,
+		HTTPClient:  &http.Client{Jar: jar},
+		TLEClass:    "gp",
+		stats:       &clientStats{},
+		limiter: newRateLimiter(
+			rateLimit{DefaultRequestsPerMinute, time.Minute},
+			rateLimit{DefaultRequestsPerHour, time.Hour}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Accounts send requests the way the client would, but keep their
+	// sessions apart.
+	for _, account := range c.credentials {
+		a := &Client{
+			LoginURL:    loginURL,
+			APIRoot:     apiRoot,
+			Identity:    account.Identity,
+			Password:    account.Password,
+			MaxAttempts: c.MaxAttempts,
+			HTTPClient:  c.HTTPClient,
+			limiter:     newRateLimiter(c.limiter.limits...),
+			stats:       c.stats,
+		}
+		if httpClient, ok := c.HTTPClient.(*http.Client); ok {
+			jar, _ := cookiejar.New(nil)
+			a.HTTPClient = &http.Client{Jar: jar, Transport: httpClient.Transport, Timeout: httpClient.Timeout}
+		}
+		c.accounts = append(c.accounts, a)
+	}
+	return c
+}
+
+// SetRateLimits replaces the client's request budgets, or each of its
+// accounts'. A non-positive value removes that limit.
+func (c *Client) SetRateLimits(perMinute int, perHour int) {
+	c.limiter = newRateLimiter(
+		rateLimit{perMinute, time.Minute},
+		rateLimit{perHour, time.Hour})
+	for _, account := range c.accounts {
+		account.SetRateLimits(perMinute, perHour)
+	}
+}
+
+// Login posts the client's credentials to Space Track, storing the returned
+// session cookie in the client's jar. A client with accounts logs each in,
+// leaving out those whose credentials are rejected, and only fails if none
+// can log in.
+func (c *Client) Login() error {
+	if len(c.accounts) > 0 {
+		return c.loginAccounts()
+	}
+	return c.login(context.Background())
+}
+
+// login posts the client's own credentials, once its rate limits allow,
+// giving up when ctx is done.
+func (c *Client) login(ctx context.Context) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"identity": {c.Identity},
+		"password": {c.Password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.LoginURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("logging in to %s: %s", c.LoginURL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("logging in to %s: %v", c.LoginURL, err)
+	}
+
+	// Success is an empty JSON string; bad credentials get {"Login":"Failed"}.
+	var result struct{ Login string }
+	if json.Unmarshal(body, &result) == nil && result.Login == "Failed" {
+		return ErrAuthFailed
+	}
+	if isHTML(resp) {
+		return ErrAuthFailed
+	}
+
+	return nil
+}
+
+// QueryPath builds the path of a Query for class, relative to the API root,
+// from alternating predicate names and values, such as "orderby",
+// "EPOCH asc". Values are escaped, keeping the commas that separate
+// alternatives. It fails if the last name has no value.
+func QueryPath(class string, predicates ...string) (string, error) {
+	if len(predicates)%2 != 0 {
+		return "", fmt.Errorf("query of %s: predicate %s has no value", class, predicates[len(predicates)-1])
+	}
+
+	path := "/query/class/" + url.PathEscape(class)
+	for i := 0; i < len(predicates); i += 2 {
+		values := strings.Split(predicates[i+1], ",")
+		for j, v := range values {
+			values[j] = url.PathEscape(v)
+		}
+		path += "/" + url.PathEscape(predicates[i]) + "/" + strings.Join(values, ",")
+	}
+	return path, nil
+}
+
+// queryPath is QueryPath for predicates written out in pairs, which can't
+// fail.
+func queryPath(class string, predicates ...string) string {
+	path, err := QueryPath(class, predicates...)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// CheckNORADIDs returns an error naming the first of noradIDs that isn't a
+// catalog number, one to nine digits, so that nothing else can slip into a
+// query's predicates.
+func CheckNORADIDs(noradIDs ...string) error {
+	for _, id := range noradIDs {
+		if len(id) == 0 || len(id) > 9 || strings.Trim(id, "0123456789") != "" {
+			return fmt.Errorf("bad NORAD ID %q, want a catalog number", id)
+		}
+	}
+	return nil
+}
+
+// LatestTLEQueryPath builds the path of a query for just the newest element
+// set of each of the comma-separated noradIDs, from the gp class unless
+// class is the deprecated tle, in two-line form.
+func LatestTLEQueryPath(class string, noradIDs string) string {
+	if class == "tle" {
+		return queryPath("tle_latest",
+			"ORDINAL", "1",
+			"NORAD_CAT_ID", noradIDs,
+			"format", "tle",
+			"metadata", "false")
+	}
+	return queryPath("gp", "NORAD_CAT_ID", noradIDs, "format", "tle", "metadata", "false")
+}
+
+// queryRecords queries Space Track for class with predicates, as QueryPath
+// takes them, and decodes the JSON records it sends into records, a pointer
+// to a slice of structs. Space Track sends every value as a string, or null
+// if empty, so the structs' fields should be strings, which nulls leave
+// empty.
+func (c *Client) queryRecords(class string, records any, predicates ...string) error {
+	path, err := QueryPath(class, append(predicates, "format", "json", "metadata", "false")...)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Query(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp, records); err != nil {
+		return fmt.Errorf("decoding %s records: %v", class, err)
+	}
+	return nil
+}
+
+// spaceTrackTime formats t the way Space Track writes dates, in UTC.
+func spaceTrackTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// loginAccounts logs in each of the client's accounts.
+func (c *Client) loginAccounts() error {
+	var err error
+	loggedIn := 0
+	for _, account := range c.accounts {
+		if err = account.Login(); err != nil {
+			if errors.Is(err, ErrAuthFailed) {
+				account.disabled.Store(true)
+			}
+			slog.Warn("Space Track account can't log in", "identity", account.Identity, "err", err)
+			continue
+		}
+		account.disabled.Store(false)
+		loggedIn++
+	}
+	if loggedIn == 0 {
+		return err
+	}
+	slog.Info("Logged in to Space Track", "accounts", loggedIn)
+	return nil
+}
+
+// Query fetches path, relative to the client's APIRoot, with a GET using the
+// session established by Login. Only Login sends the credentials.
+func (c *Client) Query(path string) ([]byte, error) {
+	return c.QueryContext(context.Background(), path)
+}
+
+// QueryContext is like Query but gives up when ctx is done. It blocks until
+// the client's rate limits allow another request, and fails immediately if
+// that would be after ctx's deadline. Network errors and 5xx or 429
+// responses are retried with exponential backoff, up to MaxAttempts tries.
+// Responses in the client's cache are returned without any request.
+func (c *Client) QueryContext(ctx context.Context, path string) ([]byte, error) {
+	queryURL := c.APIRoot + path
+	if body, ok := c.readCache(queryURL); ok {
+		return body, nil
+	}
+	slog.Debug("Querying Space Track", "url", queryURL)
+
+	for attempt := 1; ; attempt++ {
+		body, err := c.get(ctx, queryURL)
+		if err == nil {
+			c.writeCache(queryURL, body)
+			return body, nil
+		}
+
+		retry, ok := err.(*retryableError)
+		if !ok || attempt >= c.MaxAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := retry.after
+		if wait <= 0 {
+			wait = backoff(attempt)
+		}
+		slog.Warn("Query failed, retrying", "err", err, "attempt", attempt, "wait", wait.Round(time.Millisecond))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// get makes a single rate-limited GET request. Failures worth trying again
+// are returned as a *retryableError.
+func (c *Client) get(ctx context.Context, queryURL string) ([]byte, error) {
+	if len(c.accounts) > 0 {
+		return c.getFromAccounts(ctx, queryURL)
+	}
+	if err := c.spendRequest(); err != nil {
+		return nil, err
+	}
+	waitStart := time.Now()
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	c.stats.limiterWait.Add(int64(time.Since(waitStart)))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.stats.requests.Add(1)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		c.stats.fail("error")
+		return nil, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		c.stats.fail(strconv.Itoa(resp.StatusCode))
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &retryableError{
+			err:   statusError(queryURL, resp),
+			after: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	case resp.StatusCode >= 500:
+		return nil, &retryableError{err: statusError(queryURL, resp)}
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: %v", ErrAuthFailed, statusError(queryURL, resp))
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, statusError(queryURL, resp)
+	}
+
+	// Without a valid session, queries are answered with the HTML login page.
+	if isHTML(resp) {
+		c.stats.fail("auth")
+		return nil, ErrAuthFailed
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		c.stats.fail("error")
+		return nil, &retryableError{err: fmt.Errorf("reading response to %s: %v", queryURL, err)}
+	}
+
+	c.stats.bytes.Add(int64(len(body)))
+	c.stats.lastSuccess.Store(time.Now().Unix())
+	return body, nil
+}
+
+// isHTML reports whether resp claims to be a web page rather than data.
+func isHTML(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html")
+}
+
+// spendRequest counts a query request against MaxRequests, failing with
+// ErrBudgetExhausted once they're all spent.
+func (c *Client) spendRequest() error {
+	if c.MaxRequests > 0 && c.requests.Add(1) > int64(c.MaxRequests) {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
+
+// getFromAccounts makes the request with the next of the client's accounts
+// in turn. An account whose session is rejected logs in again, within its
+// rate limits, and is left out from then on if it's rejected again. Every
+// query request an account makes counts against the client's MaxRequests.
+func (c *Client) getFromAccounts(ctx context.Context, queryURL string) ([]byte, error) {
+	for i := 0; i < len(c.accounts); i++ {
+		account := c.accounts[c.nextAccount.Add(1)%uint64(len(c.accounts))]
+		if account.disabled.Load() {
+			continue
+		}
+		if err := c.spendRequest(); err != nil {
+			return nil, err
+		}
+		body, err := account.get(ctx, queryURL)
+		if !errors.Is(err, ErrAuthFailed) {
+			return body, err
+		}
+		if err := account.login(ctx); err == nil {
+			if err := c.spendRequest(); err != nil {
+				return nil, err
+			}
+			if body, err = account.get(ctx, queryURL); !errors.Is(err, ErrAuthFailed) {
+				return body, err
+			}
+		} else if ctx.Err() != nil {
+			return nil, err
+		}
+		slog.Warn("Space Track rejected account, leaving it out", "identity", account.Identity)
+		account.disabled.Store(true)
+	}
+	return nil, ErrAuthFailed
+}
+
+// statusError describes a response to queryURL with an error status,
+// quoting the start of its body, where Space Track says what went wrong.
+func statusError(queryURL string, resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if snippet == "" {
+		return fmt.Errorf("%s: %s", queryURL, resp.Status)
+	}
+	if len(snippet) > 200 {
+		snippet = snippet[:200] + "..."
+	}
+	return fmt.Errorf("%s: %s: %q", queryURL, resp.Status, snippet)
+}
+
+// retryableError is a failed request that may succeed if repeated, after
+// waiting at least as long as the server asked, if it did.
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// backoffStart is how long backoff waits after the first attempt, before
+// randomizing. Tests shorten it.
+var backoffStart = time.Second
+
+// backoff returns how long to wait before the attempt following the given
+// one: exponential from backoffStart, capped at a minute, with the lower
+// half randomized so concurrent clients spread out.
+func backoff(attempt int) time.Duration {
+	d := time.Minute
+	if attempt < 7 && backoffStart<<uint(attempt-1) < d {
+		d = backoffStart << uint(attempt-1)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date. It returns zero if the header is absent or malformed.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
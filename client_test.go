@@ -0,0 +1,291 @@
+package satfetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	// Retry quickly, so tests of retries needn't wait seconds.
+	backoffStart = time.Millisecond
+}
+
+// newTestClient returns a client of a server answering queries with
+// handler, without rate limits.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL+"/ajaxauth/login", srv.URL+"/basicspacedata", "user", "pass")
+	client.SetRateLimits(0, 0)
+	return client
+}
+
+// statuses returns a handler answering with each of codes in turn, and
+// 200 with body after them, counting the requests in n.
+func statuses(n *atomic.Int32, body string, codes ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		i := int(n.Add(1)) - 1
+		if i < len(codes) {
+			if codes[i] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			http.Error(w, http.StatusText(codes[i]), codes[i])
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func TestQueryRetries(t *testing.T) {
+	var n atomic.Int32
+	client := newTestClient(t, statuses(&n, `[]`, http.StatusServiceUnavailable, http.StatusServiceUnavailable))
+
+	body, err := client.Query("/query/class/gp")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if string(body) != `[]` {
+		t.Errorf("Query = %q, want []", body)
+	}
+	if n.Load() != 3 {
+		t.Errorf("made %d requests, want 3", n.Load())
+	}
+}
+
+func TestQueryGivesUp(t *testing.T) {
+	var n atomic.Int32
+	client := newTestClient(t, statuses(&n, `[]`, 503, 503, 503, 503, 503))
+	client.MaxAttempts = 3
+
+	if _, err := client.Query("/query/class/gp"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Query error = %v, want the 503", err)
+	}
+	if n.Load() != 3 {
+		t.Errorf("made %d requests, want 3", n.Load())
+	}
+}
+
+func TestQueryRetryAfter(t *testing.T) {
+	var n atomic.Int32
+	client := newTestClient(t, statuses(&n, `[]`, http.StatusTooManyRequests))
+
+	start := time.Now()
+	if _, err := client.Query("/query/class/gp"); err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if n.Load() != 2 {
+		t.Errorf("made %d requests, want 2", n.Load())
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("retried after %s, want the second Retry-After asked for", waited)
+	}
+}
+
+func TestQueryFailsFast(t *testing.T) {
+	for _, code := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound} {
+		t.Run(http.StatusText(code), func(t *testing.T) {
+			var n atomic.Int32
+			client := newTestClient(t, statuses(&n, `[]`, code))
+
+			_, err := client.Query("/query/class/gp")
+			if err == nil {
+				t.Errorf("Query succeeded, want the %d", code)
+			}
+			if errors.Is(err, ErrAuthFailed) != (code == http.StatusUnauthorized) {
+				t.Errorf("Query error = %v, want ErrAuthFailed only for a 401", err)
+			}
+			if n.Load() != 1 {
+				t.Errorf("made %d requests, want 1", n.Load())
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %s, want about an hour", date, got)
+	}
+}
+
+// fakeDoer answers requests with a canned response, remembering the last
+// request.
+type fakeDoer struct {
+	status      int
+	contentType string
+	body        string
+	last        *http.Request
+}
+
+func (d *fakeDoer) Do(req *http.Request) (*http.Response, error) {
+	d.last = req
+	return &http.Response{
+		StatusCode: d.status,
+		Status:     http.StatusText(d.status),
+		Header:     http.Header{"Content-Type": {d.contentType}},
+		Body:       io.NopCloser(strings.NewReader(d.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		doer    *fakeDoer
+		wantErr error
+	}{
+		{"success", &fakeDoer{status: 200, contentType: "application/json", body: `""`}, nil},
+		{"rejected", &fakeDoer{status: 200, contentType: "application/json", body: `{"Login":"Failed"}`}, ErrAuthFailed},
+		{"login page", &fakeDoer{status: 200, contentType: "text/html; charset=UTF-8", body: "<html></html>"}, ErrAuthFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("https://example.com/ajaxauth/login", "https://example.com/basicspacedata", "user", "pass")
+			client.SetRateLimits(0, 0)
+			client.HTTPClient = tt.doer
+
+			if err := client.Login(); err != tt.wantErr {
+				t.Errorf("Login error = %v, want %v", err, tt.wantErr)
+			}
+			req := tt.doer.last
+			if req == nil || req.Method != http.MethodPost || req.URL.String() != client.LoginURL {
+				t.Fatalf("Login sent %v, want a POST to %s", req, client.LoginURL)
+			}
+			req.ParseForm()
+			if req.PostForm.Get("identity") != "user" || req.PostForm.Get("password") != "pass" {
+				t.Errorf("Login posted %v, want the credentials", req.PostForm)
+			}
+		})
+	}
+}
+
+func TestQueryDoer(t *testing.T) {
+	doer := &fakeDoer{status: 200, contentType: "text/plain", body: issLine1 + "\n" + issLine2 + "\n"}
+	client := NewClient("https://example.com/ajaxauth/login", "https://example.com/basicspacedata", "user", "pass")
+	client.SetRateLimits(0, 0)
+	client.HTTPClient = doer
+
+	data, err := client.FetchTLE("25544")
+	if err != nil {
+		t.Fatalf("FetchTLE: %v", err)
+	}
+	if string(data) != doer.body {
+		t.Errorf("FetchTLE = %q, want %q", data, doer.body)
+	}
+	if want := "https://example.com/basicspacedata" + LatestTLEQueryPath(client.TLEClass, "25544"); doer.last.URL.String() != want {
+		t.Errorf("FetchTLE requested %s, want %s", doer.last.URL, want)
+	}
+
+	doer.contentType = "text/html"
+	if _, err := client.FetchTLE("25544"); err != ErrAuthFailed {
+		t.Errorf("FetchTLE of the login page: error = %v, want ErrAuthFailed", err)
+	}
+
+	doer.contentType = "text/plain"
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := client.FetchTLEsByDateRange("25544", start, start.Add(24*time.Hour)); err != nil {
+		t.Fatalf("FetchTLEsByDateRange: %v", err)
+	}
+	if want := "/query/class/gp_history/NORAD_CAT_ID/25544/EPOCH/2024-01-01%2000:00:00--2024-01-02%2000:00:00/orderby/EPOCH%20asc/format/tle/metadata/false"; doer.last.URL.EscapedPath() != "/basicspacedata"+want {
+		t.Errorf("FetchTLEsByDateRange requested %s, want %s", doer.last.URL.EscapedPath(), want)
+	}
+}
+
+// failingDoer fails the test if it's asked to send anything.
+type failingDoer struct{ t *testing.T }
+
+func (d failingDoer) Do(req *http.Request) (*http.Response, error) {
+	d.t.Errorf("sent %s %s, want nothing sent", req.Method, req.URL)
+	return nil, errors.New("no requests allowed")
+}
+
+func TestMalformedNORADIDs(t *testing.T) {
+	for _, ids := range [][]string{
+		{"25544", "28129/format/html"},
+		{"25544", ""},
+		{"-5"},
+		{"1234567890"},
+	} {
+		if err := CheckNORADIDs(ids...); err == nil {
+			t.Errorf("CheckNORADIDs(%q) succeeded", ids)
+		}
+	}
+	if err := CheckNORADIDs("5", "25544", "000000005"); err != nil {
+		t.Errorf("CheckNORADIDs of good IDs: %v", err)
+	}
+
+	client := NewClient("https://example.com/ajaxauth/login", "https://example.com/basicspacedata", "user", "pass")
+	client.HTTPClient = failingDoer{t}
+	if _, err := client.FetchTLE("25544,28129"); err == nil {
+		t.Error("FetchTLE of two IDs in one succeeded")
+	}
+	if _, err := client.FetchTLEsByDateRange("25544/format/html", time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Error("FetchTLEsByDateRange with a malformed ID succeeded")
+	}
+
+	celestrak := NewCelestrakSource()
+	celestrak.HTTPClient = failingDoer{t}
+	if _, err := celestrak.FetchTLE("25544&FORMAT=JSON"); err == nil {
+		t.Error("CelestrakSource.FetchTLE with a malformed ID succeeded")
+	}
+}
+
+func TestQueryPath(t *testing.T) {
+	path, err := QueryPath("gp", "NORAD_CAT_ID", "25544,28129", "orderby", "EPOCH asc")
+	if want := "/query/class/gp/NORAD_CAT_ID/25544,28129/orderby/EPOCH%20asc"; err != nil || path != want {
+		t.Errorf("QueryPath = %q, %v, want %q", path, err, want)
+	}
+	if _, err := QueryPath("gp", "NORAD_CAT_ID", "25544", "orderby"); err == nil {
+		t.Error("QueryPath with a name without a value succeeded")
+	}
+}
+
+func TestAccountsRelogInWithinBudget(t *testing.T) {
+	var logins, queries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login") {
+			logins.Add(1)
+			return
+		}
+		// Every session is rejected, with the login page.
+		queries.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL+"/ajaxauth/login", srv.URL+"/basicspacedata", "", "",
+		WithAccounts(Account{"a", "pass"}, Account{"b", "pass"}))
+	client.SetRateLimits(0, 0)
+	client.MaxRequests = 3
+
+	// One account's query, its query after logging in again, and the other
+	// account's query leave no budget for asking the other again.
+	if _, err := client.Query("/query/class/gp"); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Query error = %v, want ErrBudgetExhausted", err)
+	}
+	if queries.Load() != 3 || logins.Load() != 2 {
+		t.Errorf("made %d queries and %d logins, want 3 and 2", queries.Load(), logins.Load())
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/deorbit/satfetch"
+)
+
+// runBoxscore implements the boxscore subcommand, reporting each country's
+// objects on orbit and decayed.
+func runBoxscore(args []string) {
+	fs := flag.NewFlagSet("boxscore", flag.ExitOnError)
+	format := fs.String("format", "table", "Output format: table or json.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	if *format != "table" && *format != "json" {
+		fatalf("Unknown -format %q, want table or json", *format)
+	}
+
+	client := satfetch.NewClientFromConfig(config)
+	if err := client.Login(); err != nil {
+		fatal(err)
+	}
+	boxscore, err := client.FetchBoxscore()
+	if err != nil {
+		fatal(err)
+	}
+
+	rows := make([][]string, len(boxscore))
+	for i, b := range boxscore {
+		rows[i] = []string{b.Country, b.OrbitalPayloads, b.OrbitalRockets, b.OrbitalDebris, b.OrbitalTBA, b.OrbitalTotal,
+			b.DecayedPayloads, b.DecayedRockets, b.DecayedDebris, b.DecayedTotal, b.CountryTotal}
+	}
+	header := []string{"COUNTRY", "PAYLOADS", "ROCKET BODIES", "DEBRIS", "TBA", "ON ORBIT",
+		"DECAYED PAYLOADS", "DECAYED ROCKET BODIES", "DECAYED DEBRIS", "DECAYED", "TOTAL"}
+	if err := writeReport(*format, boxscore, true, header, rows); err != nil {
+		fatal(err)
+	}
+}
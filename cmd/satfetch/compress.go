@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/deorbit/satfetch"
+)
+
+// gzipOutput makes fetches write their SATCAT and TLE files gzip-compressed,
+// with satfetch.GzipExt added to their names. Set by the -gzip flag.
+var gzipOutput bool
+
+// outputLayout is how TLE files are laid out in the TLE directory: flat, all
+// in the directory itself, or sharded, in subdirectories named by shardDir
+// so that no one directory holds tens of thousands of files. Set by the
+// -output-layout flag.
+var outputLayout = "flat"
+
+// shardDir returns the name of the subdirectory of a sharded TLE directory
+// that noradID's files go in: the first two digits of its five-digit
+// catalog number, so 25544 goes in 25 and 5 in 00.
+func shardDir(noradID string) string {
+	if len(noradID) < 5 {
+		noradID = strings.Repeat("0", 5-len(noradID)) + noradID
+	}
+	return noradID[:2]
+}
+
+// satelliteDir returns the directory in destdir that noradID's TLE files go
+// in with outputLayout.
+func satelliteDir(destdir string, noradID string) string {
+	if outputLayout == "sharded" {
+		return filepath.Join(destdir, shardDir(noradID))
+	}
+	return destdir
+}
+
+// tleFilename returns the name of the TLE file for noradID in destdir, in
+// tleFormat and compressed if gzipOutput is set.
+func tleFilename(destdir string, noradID string) string {
+	return tleFileIn(satelliteDir(destdir, noradID), noradID)
+}
+
+// tleYearDir returns the directory noradID's element sets go in, a file
+// per year, when split by year.
+func tleYearDir(destdir string, noradID string) string {
+	return filepath.Join(satelliteDir(destdir, noradID), noradID)
+}
+
+// tleYearFilename returns the name of the file for noradID's element sets
+// with epochs in year, when split by year, in tleFormat and compressed if
+// gzipOutput is set.
+func tleYearFilename(destdir string, noradID string, year int) string {
+	return tleFileIn(tleYearDir(destdir, noradID), strconv.Itoa(year))
+}
+
+// tleFileIn returns the name of the TLE file called name in dir.
+func tleFileIn(dir string, name string) string {
+	filename := dir + "/" + name + tleFileExt()
+	if gzipOutput {
+		filename += satfetch.GzipExt
+	}
+	return filename
+}
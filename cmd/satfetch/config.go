@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/deorbit/satfetch"
+)
+
+// credentialFlags registers the flags locating Space Track credentials on
+// fs, returning a function that resolves them once fs is parsed.
+func credentialFlags(fs *flag.FlagSet) func() (satfetch.Config, error) {
+	configFile := fs.String("config", "", "Config file with Space Track credentials.\n"+
+		"Defaults to ~/"+satfetch.DefaultConfigFile+" if it exists.")
+	identity := fs.String("identity", "", "Space Track user name. Overrides the config file and SPACETRACKUSER.")
+	passwordFile := fs.String("password-file", "", "File holding the Space Track password.\n"+
+		"Overrides the config file and SPACETRACKPASS.")
+
+	return func() (satfetch.Config, error) {
+		return satfetch.ResolveConfig(*configFile, *identity, *passwordFile)
+	}
+}
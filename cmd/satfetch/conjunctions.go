@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// enrichConjunctions names each conjunction's objects as the SATCAT does,
+// where it has them.
+func enrichConjunctions(conjunctions []satfetch.Conjunction, index *satfetch.SatcatIndex) {
+	for i := range conjunctions {
+		c := &conjunctions[i]
+		if row, ok := index.ByNORAD(c.Sat1ID); ok {
+			c.Sat1Name = satcatName(row)
+		}
+		if row, ok := index.ByNORAD(c.Sat2ID); ok {
+			c.Sat2Name = satcatName(row)
+		}
+	}
+}
+
+// runConjunctions implements the conjunctions subcommand, reporting the
+// close approaches predicted within some days from now.
+func runConjunctions(args []string) {
+	fs := flag.NewFlagSet("conjunctions", flag.ExitOnError)
+	days := fs.Int("days", 3, "Report conjunctions within this many days from now.")
+	satcatFilename := fs.String("satcat", "", "SATCAT file to name the objects from. CSV, or JSON if the filename\n"+
+		"ends in .json.")
+	format := fs.String("format", "table", "Output format: table or json.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	if *format != "table" && *format != "json" {
+		fatalf("Unknown -format %q, want table or json", *format)
+	}
+
+	index := satfetch.NewSatcatIndex(nil)
+	if *satcatFilename != "" {
+		satcatRows, err := loadSATCAT(*satcatFilename)
+		if err != nil {
+			fatal(err)
+		}
+		index = satfetch.NewSatcatIndex(satcatRows)
+	}
+
+	client := satfetch.NewClientFromConfig(config)
+	if err := client.Login(); err != nil {
+		fatal(err)
+	}
+	now := time.Now()
+	conjunctions, err := client.FetchConjunctions(now, now.Add(time.Duration(*days)*24*time.Hour))
+	if err != nil {
+		fatal(err)
+	}
+	enrichConjunctions(conjunctions, index)
+
+	rows := make([][]string, len(conjunctions))
+	for i, c := range conjunctions {
+		missDistance, probability := "-", "-"
+		if c.MissDistanceM != nil {
+			missDistance = fmt.Sprintf("%.0f", *c.MissDistanceM)
+		}
+		if c.Probability != nil {
+			probability = fmt.Sprintf("%.3g", *c.Probability)
+		}
+		rows[i] = []string{c.TCA.Format("2006-01-02 15:04:05"), c.Sat1ID, c.Sat1Name, c.Sat2ID, c.Sat2Name,
+			missDistance, probability}
+	}
+	header := []string{"TCA", "NORAD ID", "NAME", "NORAD ID", "NAME", "MISS M", "PROBABILITY"}
+	if err := writeReport(*format, conjunctions, false, header, rows); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// runCZML implements the czml subcommand, writing the satellites in a TLE
+// directory out as a CZML scene for CesiumJS.
+func runCZML(args []string) {
+	fs := flag.NewFlagSet("czml", flag.ExitOnError)
+	tleDir := fs.String("tle-dir", "./tle", "Directory of TLE files of the satellites to show.")
+	satcatFilename := fs.String("satcat", "", "SATCAT file to name the satellites from. CSV, or JSON if the filename ends\n"+
+		"in .json. Satellites are labeled with their NORAD IDs without one.")
+	startFlag := fs.String("start", "", "RFC3339 time to start the scene at. Defaults to now.")
+	stopFlag := fs.String("stop", "", "RFC3339 time to end the scene at. Defaults to a day after -start.")
+	step := fs.Duration("step", time.Minute, "Time between position samples.")
+	out := fs.String("out", StdoutDir, "File to write the CZML to. Defaults to standard output.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	if *step <= 0 {
+		fatal("-step must be positive")
+	}
+
+	start := time.Now().UTC().Truncate(time.Second)
+	var err error
+	if *startFlag != "" {
+		if start, err = time.Parse(time.RFC3339, *startFlag); err != nil {
+			fatalf("bad -start: %v", err)
+		}
+	}
+	stop := start.Add(24 * time.Hour)
+	if *stopFlag != "" {
+		if stop, err = time.Parse(time.RFC3339, *stopFlag); err != nil {
+			fatalf("bad -stop: %v", err)
+		}
+	}
+	if !stop.After(start) {
+		fatal("-stop must be after -start")
+	}
+
+	tles, err := loadTLEDir(*tleDir)
+	if err != nil {
+		fatal(err)
+	}
+	names := make(map[uint64]string)
+	if *satcatFilename != "" {
+		satcatRows, err := loadSATCAT(*satcatFilename)
+		if err != nil {
+			fatal(err)
+		}
+		for _, row := range satcatRows {
+			if noradID, err := strconv.ParseUint(row.NORADID, 10, 64); err == nil {
+				names[noradID] = satcatName(row)
+			}
+		}
+	}
+
+	if *out == StdoutDir {
+		if err := satfetch.WriteCZML(os.Stdout, tles, names, start, stop, *step); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	var buf bytes.Buffer
+	if err := satfetch.WriteCZML(&buf, tles, names, start, stop, *step); err != nil {
+		fatal(err)
+	}
+	slog.Info("Writing CZML", "file", *out, "satellites", len(tles))
+	if err := satfetch.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// enrichDecays fills in each decay's country and object type from the
+// SATCAT, where it has the object.
+func enrichDecays(decays []satfetch.Decay, index *satfetch.SatcatIndex) {
+	for i := range decays {
+		row, ok := index.ByNORAD(decays[i].NORADID)
+		if !ok {
+			continue
+		}
+		if row.Country != "" {
+			decays[i].Country = row.Country
+		}
+		decays[i].ObjectType = row.ObjectType
+	}
+}
+
+// runDecay implements the decay subcommand, reporting objects that have
+// reentered or are predicted to within some days of now.
+func runDecay(args []string) {
+	fs := flag.NewFlagSet("decay", flag.ExitOnError)
+	days := fs.Int("days", 7, "Report decays within this many days of now, past or predicted.")
+	satcatFilename := fs.String("satcat", "", "SATCAT file to add each object's country and type from.\n"+
+		"CSV, or JSON if the filename ends in .json.")
+	format := fs.String("format", "table", "Output format: table or json.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	if *format != "table" && *format != "json" {
+		fatalf("Unknown -format %q, want table or json", *format)
+	}
+
+	index := satfetch.NewSatcatIndex(nil)
+	if *satcatFilename != "" {
+		satcatRows, err := loadSATCAT(*satcatFilename)
+		if err != nil {
+			fatal(err)
+		}
+		index = satfetch.NewSatcatIndex(satcatRows)
+	}
+
+	client := satfetch.NewClientFromConfig(config)
+	if err := client.Login(); err != nil {
+		fatal(err)
+	}
+	window := time.Duration(*days) * 24 * time.Hour
+	now := time.Now()
+	decays, err := client.FetchDecays(now.Add(-window), now.Add(window))
+	if err != nil {
+		fatal(err)
+	}
+	enrichDecays(decays, index)
+
+	rows := make([][]string, len(decays))
+	for i, d := range decays {
+		rows[i] = []string{d.NORADID, d.ObjectName, d.DecayEpoch, d.MessageType, d.Country, d.ObjectType}
+	}
+	header := []string{"NORAD ID", "NAME", "DECAY EPOCH", "TYPE", "COUNTRY", "OBJECT TYPE"}
+	if err := writeReport(*format, decays, false, header, rows); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"text/tabwriter"
+
+	"github.com/deorbit/satfetch"
+)
+
+// SatcatChange is a field of a SATCAT row that differs between snapshots.
+type SatcatChange struct {
+	NORADID string `json:"noradid"`
+	Name    string `json:"name"`
+	Field   string `json:"field"` // SatcatRow's json tag for it
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+// SatcatDiff is what changed between two SATCAT snapshots.
+type SatcatDiff struct {
+	Added   []satfetch.SatcatRow `json:"added"`
+	Removed []satfetch.SatcatRow `json:"removed"`
+	Changed []SatcatChange       `json:"changed"`
+}
+
+// DiffSatcat compares two SATCAT snapshots row by row, matching rows by
+// NORAD ID. Added and changed rows are in newRows' order, removed ones in
+// oldRows'.
+func DiffSatcat(oldRows []satfetch.SatcatRow, newRows []satfetch.SatcatRow) SatcatDiff {
+	diff := SatcatDiff{
+		Added:   []satfetch.SatcatRow{},
+		Removed: []satfetch.SatcatRow{},
+		Changed: []SatcatChange{},
+	}
+	oldIndex := satfetch.NewSatcatIndex(oldRows)
+	newIndex := satfetch.NewSatcatIndex(newRows)
+
+	t := reflect.TypeOf(satfetch.SatcatRow{})
+	for _, row := range newRows {
+		old, ok := oldIndex.ByNORAD(row.NORADID)
+		if !ok {
+			diff.Added = append(diff.Added, row)
+			continue
+		}
+
+		oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(row)
+		for i := 0; i < t.NumField(); i++ {
+			if o, n := oldValue.Field(i).String(), newValue.Field(i).String(); o != n {
+				diff.Changed = append(diff.Changed, SatcatChange{
+					NORADID: row.NORADID,
+					Name:    satcatName(row),
+					Field:   t.Field(i).Tag.Get("json"),
+					Old:     o,
+					New:     n,
+				})
+			}
+		}
+	}
+
+	for _, row := range oldRows {
+		if _, ok := newIndex.ByNORAD(row.NORADID); !ok {
+			diff.Removed = append(diff.Removed, row)
+		}
+	}
+	return diff
+}
+
+// runDiff implements the diff subcommand, reporting the differences between
+// two SATCAT files.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "table", "Output format: table or json.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: satfetch diff [flags] old-satcat new-satcat")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "table" && *format != "json" {
+		fatalf("Unknown -format %q, want table or json", *format)
+	}
+
+	oldRows, err := loadSATCAT(fs.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	newRows, err := loadSATCAT(fs.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+	diff := DiffSatcat(oldRows, newRows)
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tNORAD ID\tNAME\tFIELD\tOLD\tNEW")
+	for _, row := range diff.Added {
+		fmt.Fprintf(w, "added\t%s\t%s\t\t\t\n", row.NORADID, satcatName(row))
+	}
+	for _, row := range diff.Removed {
+		fmt.Fprintf(w, "removed\t%s\t%s\t\t\t\n", row.NORADID, satcatName(row))
+	}
+	for _, c := range diff.Changed {
+		fmt.Fprintf(w, "changed\t%s\t%s\t%s\t%q\t%q\n", c.NORADID, c.Name, c.Field, c.Old, c.New)
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
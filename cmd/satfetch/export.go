@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/deorbit/satfetch"
+)
+
+// tleFormat is how TLE files are written: "text", as .tle files of two-line
+// element sets, "json", as .json files holding an array of the parsed TLEs,
+// or "xml", as .xml files of the CCSDS OMMs Space Track sends, as sent. Set
+// by the -tle-format flag.
+var tleFormat = "text"
+
+// tleFields, if set, are the json names of the only TLE fields written in
+// tleFormat json. Set by the -fields flag.
+var tleFields []string
+
+// checkTLEFields returns an error listing the valid names if any of fields
+// isn't the json name of a TLE field.
+func checkTLEFields(fields []string) error {
+	t := reflect.TypeOf(satfetch.TLE{})
+	var valid []string
+	isValid := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("json"); name != "" {
+			valid = append(valid, name)
+			isValid[name] = true
+		}
+	}
+	for _, field := range fields {
+		if !isValid[field] {
+			return fmt.Errorf("unknown TLE field %q, want some of %s", field, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+// selectTLEFields returns each of tles as a map of just the tleFields, for
+// encoding.
+func selectTLEFields(tles []satfetch.TLE) ([]map[string]json.RawMessage, error) {
+	selected := make([]map[string]json.RawMessage, len(tles))
+	for i, tle := range tles {
+		data, err := json.Marshal(tle)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		selected[i] = make(map[string]json.RawMessage, len(tleFields))
+		for _, field := range tleFields {
+			selected[i][field] = all[field]
+		}
+	}
+	return selected, nil
+}
+
+// tleFileExt returns the extension of TLE files written in tleFormat.
+func tleFileExt() string {
+	switch tleFormat {
+	case "json":
+		return ".json"
+	case "xml":
+		return ".xml"
+	}
+	return ".tle"
+}
+
+// encodeTLEs converts TLE text to tleFormat text or json for writing to a
+// file. OMMs are written as Space Track sent them, by writeOMMFile.
+func encodeTLEs(tles []byte) ([]byte, error) {
+	if tleFormat == "text" {
+		return tles, nil
+	}
+
+	parsed, err := satfetch.ParseTLEText(tles)
+	if err != nil {
+		return nil, err
+	}
+	// Write [] rather than null when there are none.
+	if parsed == nil {
+		parsed = []satfetch.TLE{}
+	}
+	var v interface{} = parsed
+	if len(tleFields) > 0 {
+		if v, err = selectTLEFields(parsed); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// decodeTLEs converts the contents of a file written in tleFormat back to
+// TLE text.
+func decodeTLEs(data []byte) ([]byte, error) {
+	if tleFormat == "text" || len(bytes.TrimSpace(data)) == 0 {
+		return data, nil
+	}
+
+	var tles []satfetch.TLE
+	if tleFormat == "xml" {
+		var err error
+		if tles, err = satfetch.ParseOMM(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &tles); err != nil {
+		return nil, fmt.Errorf("decoding TLE JSON: %v", err)
+	}
+	var buf bytes.Buffer
+	for _, tle := range tles {
+		buf.WriteString(tle.TwoLine())
+	}
+	return buf.Bytes(), nil
+}
+
+// tleQueryFormat returns the format to ask Space Track for element sets in:
+// the OMMs tleFormat xml writes, or else two-line text.
+func tleQueryFormat() string {
+	if tleFormat == "xml" {
+		return "xml"
+	}
+	return "tle"
+}
+
+// checkTLEResponse returns an error unless resp is empty or holds element
+// sets in tleQueryFormat, so error pages aren't written out as TLEs.
+func checkTLEResponse(resp []byte) error {
+	if tleFormat != "xml" {
+		return satfetch.CheckTLEResponse(resp)
+	}
+	_, err := satfetch.ParseOMM(bytes.NewReader(resp))
+	return err
+}
+
+// splitResponseByNORAD groups the element sets in a response in
+// tleQueryFormat by catalog number, as splitTLEsByNORAD does two-line text.
+func splitResponseByNORAD(resp []byte) (map[int][]byte, error) {
+	if tleFormat == "xml" {
+		return splitOMMsByNORAD(resp)
+	}
+	return splitTLEsByNORAD(resp)
+}
+
+// splitOMMsByNORAD splits an <ndm> of OMMs into one per catalog number,
+// holding that satellite's OMMs. The OMMs and the <ndm> around them are
+// copied byte for byte, keeping every field, not just those a TLE has.
+func splitOMMsByNORAD(resp []byte) (map[int][]byte, error) {
+	var head []byte // up to and including <ndm ...>, if any
+	byNORAD := make(map[int][]byte)
+	dec := xml.NewDecoder(bytes.NewReader(resp))
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding OMMs: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		switch {
+		case !ok:
+			continue
+		case start.Name.Local == "ndm":
+			head = resp[:dec.InputOffset()]
+			continue
+		case start.Name.Local != "omm":
+			continue
+		}
+
+		if err := dec.Skip(); err != nil {
+			return nil, fmt.Errorf("decoding OMMs: %v", err)
+		}
+		raw := resp[offset:dec.InputOffset()]
+		tles, err := satfetch.ParseOMM(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		noradID := int(tles[0].NORADID)
+		byNORAD[noradID] = append(append(byNORAD[noradID], "\n  "...), raw...)
+	}
+
+	for noradID, omms := range byNORAD {
+		if head == nil {
+			byNORAD[noradID] = append(omms[len("\n  "):], '\n')
+			continue
+		}
+		doc := append([]byte(nil), head...)
+		doc = append(doc, omms...)
+		byNORAD[noradID] = append(doc, "\n</ndm>\n"...)
+	}
+	return byNORAD, nil
+}
+
+// writeOMMFile writes the OMMs in data, in full, to filename, once they're
+// checked to decode. The manifest records the element sets they decode to.
+func writeOMMFile(filename string, noradId string, data []byte) error {
+	tles, err := satfetch.ParseOMM(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("not writing %s: %v", filename, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), dirMode()); err != nil {
+		return err
+	}
+
+	slog.Info("Writing TLEs", "file", filename)
+	if err := satfetch.WriteFile(filename, data, fileMode); err != nil {
+		return err
+	}
+	var text bytes.Buffer
+	for _, tle := range tles {
+		text.WriteString(tle.Text())
+	}
+	return recordManifestEntry(filename, noradId, text.Bytes(), data)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/deorbit/satfetch"
+)
+
+// runFilter implements the filter subcommand, writing the rows of a SATCAT
+// that pass the SATCAT filter flags out as CSV.
+func runFilter(args []string) {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	satcatFilename := fs.String("satcat", "", "SATCAT file to filter. CSV, or JSON if the filename ends in .json.")
+	out := fs.String("out", StdoutDir, "File to write the filtered SATCAT to as CSV, gzip-compressed if it ends in .gz.\n"+
+		"Defaults to standard output.")
+	filterFromFlags := satcatFilterFlags(fs)
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	if *satcatFilename == "" {
+		fatal("filter needs -satcat")
+	}
+
+	satcatRows, err := loadSATCAT(*satcatFilename)
+	if err != nil {
+		fatal(err)
+	}
+	filter := filterFromFlags()
+	satcatRows = satfetch.FilterSatcat(satcatRows, filter)
+	slog.Info("Filtered SATCAT", "entries", len(satcatRows), "filter", filter.String())
+
+	if *out == StdoutDir {
+		if err := satfetch.WriteSATCATCSV(os.Stdout, satcatRows); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	var buf bytes.Buffer
+	if err := satfetch.WriteSATCATCSV(&buf, satcatRows); err != nil {
+		fatal(err)
+	}
+	slog.Info("Writing SATCAT", "file", *out)
+	if err := satfetch.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"strings"
+
+	"github.com/deorbit/satfetch"
+)
+
+// FetchLatestTLEs writes just the newest element set of each of noradIDs to
+// its own file in destDir, asking for batchSize satellites per request.
+// Satellites Space Track has no element sets for get no file.
+func FetchLatestTLEs(client *satfetch.Client, noradIDs []string, batchSize int, destDir string) error {
+	if err := satfetch.CheckNORADIDs(noradIDs...); err != nil {
+		return err
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	for start := 0; start < len(noradIDs); start += batchSize {
+		end := start + batchSize
+		if end > len(noradIDs) {
+			end = len(noradIDs)
+		}
+
+		resp, err := client.Query(satfetch.LatestTLEQueryPath(tleClass, strings.Join(noradIDs[start:end], ",")))
+		if err != nil {
+			return err
+		}
+		slog.Info("Received latest TLEs", "first", noradIDs[start], "last", noradIDs[end-1])
+		if err := writeTLEFiles(destDir, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runLatest implements the latest subcommand, which fetches the current
+// element set of every satellite in a SATCAT, replacing their files.
+func runLatest(args []string) {
+	fs := flag.NewFlagSet("latest", flag.ExitOnError)
+	satcatFilename := fs.String("satcat", "", "SATCAT file listing the satellites to fetch.\n"+
+		"CSV, or JSON if the filename ends in .json.")
+	tleDir := fs.String("tle-dir", "./tle", "Directory to write the TLEs to, one file per NORAD ID.")
+	batchSize := fs.Int("batch-size", 100, "Max number of NORAD IDs to fetch per request.")
+	class := fs.String("class", "gp", "Space Track class to fetch from: gp, or the deprecated tle.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	satcatFilterFromFlags := satcatFilterFlags(fs)
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	if *satcatFilename == "" {
+		fatal("latest needs -satcat")
+	}
+	if *class != "gp" && *class != "tle" {
+		fatalf("Unknown -class %q, want gp or tle", *class)
+	}
+	tleClass = *class
+
+	satcatRows, err := loadSATCAT(*satcatFilename)
+	if err != nil {
+		fatal(err)
+	}
+	if filter := satcatFilterFromFlags(); filter.String() != "" {
+		satcatRows = satfetch.FilterSatcat(satcatRows, filter)
+		slog.Info("Filtered SATCAT", "entries", len(satcatRows), "filter", filter.String())
+	}
+	tleNames = satfetch.NewSatcatIndex(satcatRows)
+
+	noradIDs := make([]string, len(satcatRows))
+	for i, row := range satcatRows {
+		noradIDs[i] = row.NORADID
+	}
+
+	client := satfetch.NewClientFromConfig(config)
+	if err := client.Login(); err != nil {
+		fatal(err)
+	}
+	err = FetchLatestTLEs(client, noradIDs, *batchSize, *tleDir)
+	if *tleDir != StdoutDir {
+		if err := writeManifest(*tleDir); err != nil {
+			slog.Warn("Writing manifest failed", "err", err)
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
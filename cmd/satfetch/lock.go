@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// acquireLock creates filename holding this process's PID and host name,
+// so that a second satfetch given the same lock file refuses to run. A lock
+// left on this host by a process that has since died is stale and taken
+// over. The returned function releases the lock. It fails if the lock
+// file's filesystem doesn't honor exclusive creates, as some network
+// filesystems don't, since the lock would keep nothing out there.
+func acquireLock(filename string) (release func(), err error) {
+	if err := checkExclusiveCreate(filepath.Dir(filename)); err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	for tries := 0; tries < 2; tries++ {
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d %s\n", os.Getpid(), host)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(filename)
+				return nil, err
+			}
+			return func() { os.Remove(filename) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		// A lock just created may not have its PID written yet.
+		var pid int
+		var holder string
+		fmt.Sscan(string(data), &pid, &holder)
+		if holder != host || pid <= 0 || processAlive(pid) {
+			return nil, fmt.Errorf("%s is held by process %d on %s", filename, pid, holder)
+		}
+		slog.Warn("Taking over stale lock", "file", filename, "pid", pid)
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%s: couldn't take over stale lock", filename)
+}
+
+// checkExclusiveCreate creates a probe file in dir with O_EXCL, and again,
+// returning an error if the second create succeeds too.
+func checkExclusiveCreate(dir string) error {
+	probe := filepath.Join(dir, fmt.Sprintf(".satfetch-excl-probe-%d", os.Getpid()))
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(probe, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		switch {
+		case i == 1 && os.IsExist(err):
+			return os.Remove(probe)
+		case err != nil:
+			os.Remove(probe)
+			return err
+		}
+		f.Close()
+	}
+	os.Remove(probe)
+	return fmt.Errorf("%s: the filesystem doesn't honor exclusive creates, so a lock file there can't keep runs apart", dir)
+}
+
+// processAlive reports whether there's a process with the given PID, even
+// one this process isn't allowed to signal.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
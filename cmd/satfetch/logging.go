@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// setupLogging sends log output to stderr, dropping records below level:
+// debug, info, warn or error.
+func setupLogging(level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("bad log level %q, want debug, info, warn or error", level)
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	return nil
+}
+
+// fatal logs its arguments, formatted as by fmt.Sprint, at error level and
+// exits.
+func fatal(v ...interface{}) {
+	slog.Error(fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+// fatalf is like fatal but formats as fmt.Sprintf does.
+func fatalf(format string, v ...interface{}) {
+	slog.Error(fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+// highlight renders s in bold red if stderr is a terminal, and leaves it
+// alone otherwise so log files don't fill with escape codes.
+func highlight(s string) string {
+	if !stderrIsTerminal() || strings.Contains(os.Getenv("TERM"), "dumb") {
+		return s
+	}
+	return "\x1b[31;1m" + s + "\x1b[0m"
+}
+
+func stderrIsTerminal() bool {
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// readLocalTLEs reads noradID's TLE file in tleDir, taking whichever of the
+// plain and compressed files exists, or the latest year's if they're split
+// by year, in either outputLayout. If there's none, the error satisfies
+// os.IsNotExist.
+func readLocalTLEs(noradID string, tleDir string) ([]byte, error) {
+	var data []byte
+	var err error
+	for _, dir := range []string{tleDir, filepath.Join(tleDir, shardDir(noradID))} {
+		filename := filepath.Join(dir, noradID+".tle")
+		data, err = satfetch.ReadFile(filename)
+		if os.IsNotExist(err) {
+			data, err = satfetch.ReadFile(filename + satfetch.GzipExt)
+		}
+		if os.IsNotExist(err) {
+			years, _ := filepath.Glob(filepath.Join(dir, noradID, "[0-9][0-9][0-9][0-9].tle*"))
+			if len(years) > 0 {
+				sort.Strings(years)
+				data, err = satfetch.ReadFile(years[len(years)-1])
+			}
+		}
+		if !os.IsNotExist(err) {
+			break
+		}
+	}
+	return data, err
+}
+
+// loadBestTLE returns the element set for noradID to propagate to t, as
+// chosen by BestTLE, from its file in tleDir, compressed or not and split by
+// year or not, or the latest from source if there is no such file, logging
+// in to Space Track with config.
+func loadBestTLE(noradID string, tleDir string, source string, config satfetch.Config, t time.Time) (satfetch.TLE, error) {
+	data, err := readLocalTLEs(noradID, tleDir)
+	if os.IsNotExist(err) {
+		var src satfetch.TLESource
+		switch source {
+		case "spacetrack":
+			client := satfetch.NewClientFromConfig(config)
+			if err := client.Login(); err != nil {
+				return satfetch.TLE{}, err
+			}
+			src = client
+		case "celestrak":
+			src = satfetch.NewCelestrakSource()
+		default:
+			return satfetch.TLE{}, fmt.Errorf("unknown source %q, want spacetrack or celestrak", source)
+		}
+		data, err = src.FetchTLE(noradID)
+	}
+	if err != nil {
+		return satfetch.TLE{}, err
+	}
+
+	tles, err := satfetch.ParseTLEText(data)
+	if err != nil {
+		return satfetch.TLE{}, fmt.Errorf("NORAD ID %s: %v", noradID, err)
+	}
+	tle, ok := satfetch.BestTLE(tles, t)
+	if !ok {
+		return satfetch.TLE{}, fmt.Errorf("NORAD ID %s: no element sets", noradID)
+	}
+	return tle, nil
+}
+
+// runLook implements the look subcommand, printing look angles to a
+// satellite from a ground station, or its upcoming passes.
+func runLook(args []string) {
+	fs := flag.NewFlagSet("look", flag.ExitOnError)
+	noradID := fs.String("norad", "", "NORAD ID of the satellite to look at.")
+	lat := fs.Float64("lat", 0, "Observer latitude in degrees, north positive.")
+	lon := fs.Float64("lon", 0, "Observer longitude in degrees, east positive.")
+	alt := fs.Float64("alt", 0, "Observer altitude in metres above the WGS-84 ellipsoid.")
+	at := fs.String("time", "", "RFC3339 time to compute the angles for. Defaults to now.")
+	tleDir := fs.String("tle-dir", "./tle", "Directory to look for the satellite's TLE file in.")
+	source := fs.String("source", "spacetrack", "Where to fetch the current TLE from if there's no file for it:\n"+
+		"spacetrack or celestrak.")
+	passes := fs.Int("passes", 0, "Print this many upcoming passes from -time instead of the current look angles.")
+	minElevation := fs.Float64("min-elevation", 0, "With -passes, the elevation in degrees a pass must rise above.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	if *noradID == "" {
+		fatal("look needs -norad")
+	}
+
+	t := time.Now().UTC()
+	if *at != "" {
+		var err error
+		if t, err = time.Parse(time.RFC3339, *at); err != nil {
+			fatalf("bad -time: %v", err)
+		}
+	}
+
+	tle, err := loadBestTLE(*noradID, *tleDir, *source, config, t)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *passes > 0 {
+		found, err := satfetch.NextPasses(tle, *lat, *lon, *alt, t, *passes, *minElevation)
+		if err != nil {
+			fatal(err)
+		}
+		for _, p := range found {
+			fmt.Printf("AOS %s az %6.2f°  max el %5.2f° at %s  LOS %s az %6.2f°\n",
+				p.AOS.Format(time.RFC3339), p.AOSAzimuth,
+				p.MaxElevation, p.MaxElevationTime.Format("15:04:05"),
+				p.LOS.Format(time.RFC3339), p.LOSAzimuth)
+		}
+		return
+	}
+
+	az, el, rangeKm, err := satfetch.LookAngles(tle, *lat, *lon, *alt, t)
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("%s  az %7.2f°  el %6.2f°  range %9.1f km\n", t.Format(time.RFC3339), az, el, rangeKm)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// ManifestFile is the name of the manifest in a TLE directory.
+const ManifestFile = "manifest.json"
+
+// Manifest summarizes the TLE files in a directory, so pipelines can read
+// one file instead of every TLE file, and see which have changed.
+type Manifest struct {
+	Updated time.Time       `json:"updated"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes one TLE file.
+type ManifestEntry struct {
+	NORADID     string    `json:"noradid"`
+	File        string    `json:"file"` // relative to the TLE directory
+	ElementSets int       `json:"elementSets"`
+	NewestEpoch time.Time `json:"newestEpoch"`
+	SHA256      string    `json:"sha256"` // hex, of the contents before any compression
+	Written     time.Time `json:"written"`
+}
+
+// manifestEntries collects the entries for the TLE files written this run,
+// by file name, for writeManifest.
+var manifestEntries = struct {
+	sync.Mutex
+	byFile map[string]ManifestEntry
+}{byFile: make(map[string]ManifestEntry)}
+
+// recordManifestEntry notes that filename has been written with data, the
+// encoding of the TLE text tles.
+func recordManifestEntry(filename string, noradID string, tles []byte, data []byte) error {
+	parsed, err := satfetch.ParseTLEText(tles)
+	if err != nil {
+		return err
+	}
+	entry := ManifestEntry{
+		NORADID:     noradID,
+		File:        filename,
+		ElementSets: len(parsed),
+		Written:     time.Now().UTC(),
+	}
+	if newest, ok := satfetch.LatestTLE(parsed); ok {
+		entry.NewestEpoch = newest.EpochTime()
+	}
+	sum := sha256.Sum256(data)
+	entry.SHA256 = hex.EncodeToString(sum[:])
+
+	manifestEntries.Lock()
+	defer manifestEntries.Unlock()
+	manifestEntries.byFile[filename] = entry
+	return nil
+}
+
+// writeManifest merges the entries for the files written this run under
+// tleDir into its manifest, keeping those for files left alone.
+func writeManifest(tleDir string) error {
+	manifestEntries.Lock()
+	defer manifestEntries.Unlock()
+	if len(manifestEntries.byFile) == 0 {
+		return nil
+	}
+
+	filename := filepath.Join(tleDir, ManifestFile)
+	var m Manifest
+	data, err := os.ReadFile(filename)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	byFile := make(map[string]ManifestEntry, len(m.Files))
+	for _, entry := range m.Files {
+		byFile[entry.File] = entry
+	}
+	for path, entry := range manifestEntries.byFile {
+		rel, err := filepath.Rel(tleDir, path)
+		if err != nil {
+			return err
+		}
+		entry.File = filepath.ToSlash(rel)
+		byFile[entry.File] = entry
+	}
+
+	m.Updated = time.Now().UTC()
+	m.Files = m.Files[:0]
+	for _, entry := range byFile {
+		m.Files = append(m.Files, entry)
+	}
+	sort.Slice(m.Files, func(i, j int) bool {
+		a, _ := strconv.Atoi(m.Files[i].NORADID)
+		b, _ := strconv.Atoi(m.Files[j].NORADID)
+		if a != b {
+			return a < b
+		}
+		return m.Files[i].File < m.Files[j].File
+	})
+
+	if data, err = json.MarshalIndent(m, "", "  "); err != nil {
+		return err
+	}
+	slog.Info("Writing manifest", "file", filename, "files", len(m.Files))
+	return satfetch.WriteFile(filename, append(data, '\n'), fileMode)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// metricsHandler serves the client's and the TLE fetches' counters in the
+// Prometheus text format.
+func metricsHandler(client *satfetch.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		metric := func(name string, kind string, help string, value float64) {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+		}
+		stats := client.Stats()
+		metric("satfetch_requests_total", "counter", "Space Track query requests sent.", float64(stats.Requests))
+
+		fmt.Fprintln(w, "# HELP satfetch_request_failures_total Space Track query requests that failed, by HTTP status, auth or error.")
+		fmt.Fprintln(w, "# TYPE satfetch_request_failures_total counter")
+		statuses := make([]string, 0, len(stats.Failures))
+		for status := range stats.Failures {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "satfetch_request_failures_total{status=%q} %d\n", status, stats.Failures[status])
+		}
+
+		var lastSuccess float64
+		if !stats.LastSuccess.IsZero() {
+			lastSuccess = float64(stats.LastSuccess.Unix())
+		}
+		metric("satfetch_response_bytes_total", "counter", "Bytes of Space Track responses received.", float64(stats.ResponseBytes))
+		metric("satfetch_rate_limit_wait_seconds_total", "counter", "Time spent waiting for the rate limits.",
+			stats.LimiterWait.Seconds())
+		metric("satfetch_last_success_timestamp_seconds", "gauge", "When a Space Track query last succeeded, in Unix time.",
+			lastSuccess)
+		metric("satfetch_satellites_written_total", "counter", "Satellites whose TLEs were written.", float64(fetchStats.written.Load()))
+		metric("satfetch_satellites_skipped_total", "counter", "Satellites skipped because their TLE files exist.",
+			float64(fetchStats.skipped.Load()))
+	})
+}
+
+// serveMetrics serves metricsHandler at /metrics on addr in the background,
+// logging if it can't.
+func serveMetrics(addr string, client *satfetch.Client) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(client))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		slog.Info("Serving metrics", "addr", addr)
+		if err := server.ListenAndServe(); err != nil {
+			slog.Error("Serving metrics failed", "err", err)
+		}
+	}()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/deorbit/satfetch"
+)
+
+// fixtureTransport answers a Client's requests from files in a directory
+// instead of Space Track, so satfetch can run without a network or
+// credentials, e.g. in CI. Logins always succeed. A query's response is the
+// file named as the cache would name it, by CacheKey of the query URL, so a
+// run with -cache-dir records the fixtures for later -offline runs.
+type fixtureTransport struct {
+	dir string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method == http.MethodPost {
+		return fixtureResponse(req, http.StatusOK, "application/json", []byte(`""`)), nil
+	}
+
+	queryURL := req.URL.String()
+	filename := filepath.Join(t.dir, satfetch.CacheKey(queryURL))
+	body, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		slog.Warn("No fixture for query", "url", queryURL, "file", filename)
+		return fixtureResponse(req, http.StatusNotFound, "text/plain", []byte("no fixture "+filename+"\n")), nil
+	} else if err != nil {
+		return nil, err
+	}
+	slog.Debug("Using fixture", "url", queryURL, "file", filename)
+	return fixtureResponse(req, http.StatusOK, "text/plain", body), nil
+}
+
+// fixtureResponse returns a response to req with the given status and body.
+func fixtureResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
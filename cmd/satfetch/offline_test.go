@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/deorbit/satfetch"
+)
+
+// TestMain runs the command itself, instead of the tests, when runSatfetch
+// asks it to, so tests can drive it as a user would.
+func TestMain(m *testing.M) {
+	if os.Getenv("SATFETCH_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runSatfetch runs the command with args in dir, with no config file or
+// credentials from the environment, and returns what it wrote to stdout and
+// stderr.
+func runSatfetch(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = []string{"SATFETCH_TEST_MAIN=1", "HOME=" + dir}
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// TestOfflineFetch fetches the TLEs for the satellites in
+// testdata/satcat.csv from the fixtures in testdata, then validates and
+// propagates what was written.
+func TestOfflineFetch(t *testing.T) {
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	tleDir := filepath.Join(dir, "tle")
+
+	out, err := runSatfetch(t, dir, "-offline", "-fixtures", testdata,
+		"-satcat", filepath.Join(testdata, "satcat.csv"), "-tle", "-tle-dir", tleDir,
+		"-fetch-interval", "1ms", "-max-catalog-age", "0")
+	if err != nil {
+		t.Fatalf("satfetch -offline: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "level=WARN") || strings.Contains(out, "level=ERROR") {
+		t.Errorf("satfetch -offline warned:\n%s", out)
+	}
+
+	for _, noradID := range []string{"5", "25544", "28129"} {
+		data, err := os.ReadFile(filepath.Join(tleDir, noradID+".tle"))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if err := satfetch.VerifyTLEText(data); err != nil {
+			t.Errorf("%s.tle: %v", noradID, err)
+		}
+		tles, err := satfetch.ParseTLEText(data)
+		if err != nil || len(tles) != 1 {
+			t.Errorf("%s.tle holds %d element sets (%v), want 1", noradID, len(tles), err)
+			continue
+		}
+		if got := strconv.FormatUint(tles[0].NORADID, 10); got != noradID {
+			t.Errorf("%s.tle is for %s", noradID, got)
+		}
+		if _, _, err := tles[0].PropagateTEME(tles[0].EpochTime()); err != nil {
+			t.Errorf("%s.tle: propagating: %v", noradID, err)
+		}
+	}
+
+	if out, err := runSatfetch(t, dir, "validate", "-tle-dir", tleDir); err != nil || !strings.Contains(out, "3 good, 0 bad") {
+		t.Errorf("satfetch validate: %v\n%s", err, out)
+	}
+}
+
+// TestOfflineFetchToStdout fetches one satellite's TLEs to standard output,
+// titled with its name from the SATCAT.
+func TestOfflineFetchToStdout(t *testing.T) {
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runSatfetch(t, t.TempDir(), "-offline", "-fixtures", testdata,
+		"-satcat", filepath.Join(testdata, "satcat.csv"), "-norad", "25544", "-tle-dir", "-",
+		"-max-catalog-age", "0")
+	if err != nil {
+		t.Fatalf("satfetch -norad 25544 -tle-dir -: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "0 ISS (ZARYA)\n1 25544U") {
+		t.Errorf("satfetch -norad 25544 -tle-dir - wrote\n%s\nwant the element set titled 0 ISS (ZARYA)", out)
+	}
+}
+
+// TestFailedRunReleasesLock checks that a run failing after taking its lock
+// still removes the lock file.
+func TestFailedRunReleasesLock(t *testing.T) {
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, "satfetch.lock")
+	out, err := runSatfetch(t, dir, "-lock-file", lockFile, "-order", "sideways")
+	if err == nil || !strings.Contains(out, "Unknown -order") {
+		t.Fatalf("satfetch -order sideways: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
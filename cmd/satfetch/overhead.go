@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// loadTLEDir returns the latest element set of every satellite with a TLE
+// file in tleDir, compressed or not, split by year or not, and in either
+// outputLayout.
+func loadTLEDir(tleDir string) ([]satfetch.TLE, error) {
+	entries, err := os.ReadDir(tleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sharded satellites' files are a level down, in directories named by
+	// shardDir. Those look like the directories of satellites split by
+	// year, and one may really be one, whose files aren't in the right
+	// shard for their names, so both are looked in.
+	type dirEntry struct {
+		shard string
+		os.DirEntry
+	}
+	var all []dirEntry
+	for _, entry := range entries {
+		all = append(all, dirEntry{"", entry})
+		name := entry.Name()
+		if _, err := strconv.ParseUint(name, 10, 64); entry.IsDir() && len(name) == 2 && err == nil {
+			sharded, err := os.ReadDir(filepath.Join(tleDir, name))
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range sharded {
+				all = append(all, dirEntry{name, entry})
+			}
+		}
+	}
+
+	var tles []satfetch.TLE
+	seen := make(map[string]bool)
+	for _, entry := range all {
+		// Files are named <norad>.tle, compressed or not, and directories
+		// of files split by year just <norad>.
+		name := entry.Name()
+		noradID := strings.TrimSuffix(strings.TrimSuffix(name, satfetch.GzipExt), ".tle")
+		if entry.IsDir() && noradID != name || !entry.IsDir() && noradID == name {
+			continue
+		}
+		if _, err := strconv.ParseUint(noradID, 10, 64); err != nil || seen[noradID] {
+			continue
+		}
+		if entry.shard != "" && shardDir(noradID) != entry.shard {
+			continue
+		}
+		seen[noradID] = true
+
+		data, err := readLocalTLEs(noradID, tleDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		parsed, err := satfetch.ParseTLEText(data)
+		if err != nil {
+			return nil, fmt.Errorf("NORAD ID %s: %v", noradID, err)
+		}
+		if tle, ok := satfetch.LatestTLE(parsed); ok {
+			tles = append(tles, tle)
+		}
+	}
+	return tles, nil
+}
+
+// runOverhead implements the overhead subcommand, listing the satellites
+// in a TLE directory that are over a region, or that can be seen from it.
+func runOverhead(args []string) {
+	fs := flag.NewFlagSet("overhead", flag.ExitOnError)
+	boxFlag := fs.String("box", "", "Region to look over, as minLat,minLon,maxLat,maxLon in degrees.\n"+
+		"A box with minLon greater than maxLon crosses the antimeridian.")
+	tleDir := fs.String("tle-dir", "./tle", "Directory of TLE files of the satellites to check.")
+	at := fs.String("time", "", "RFC3339 time to check at. Defaults to now.")
+	minElev := fs.Float64("min-elevation", -1, "List the satellites at least this many degrees above the horizon from somewhere\n"+
+		"in the region, by their footprints, instead of just those over it. Off if negative.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	if *boxFlag == "" {
+		fatal("overhead needs -box")
+	}
+	box, err := satfetch.ParseLatLonBox(*boxFlag)
+	if err != nil {
+		fatal(err)
+	}
+
+	t := time.Now().UTC()
+	if *at != "" {
+		if t, err = time.Parse(time.RFC3339, *at); err != nil {
+			fatalf("bad -time: %v", err)
+		}
+	}
+
+	tles, err := loadTLEDir(*tleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if *minElev > 90 {
+		fatal("-min-elevation must be at most 90")
+	}
+
+	sort.Slice(tles, func(i, j int) bool { return tles[i].NORADID < tles[j].NORADID })
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NORAD ID\tLAT\tLON\tALT KM")
+	for _, tle := range tles {
+		lat, lon, alt, err := satfetch.SubPoint(tle, t)
+		if err != nil {
+			slog.Debug("Can't propagate", "norad", tle.NORADID, "err", err)
+			continue
+		}
+		var in bool
+		if *minElev >= 0 {
+			in = box.Covers(lat, lon, alt, *minElev)
+		} else {
+			in = box.Contains(lat, lon)
+		}
+		if in {
+			fmt.Fprintf(w, "%d\t%.2f\t%.2f\t%.1f\n", tle.NORADID, lat, lon, alt)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Progress records how far a TLE fetch has walked through a SATCAT, so an
+// interrupted run can pick up where it left off.
+type Progress struct {
+	SatcatFile string    `json:"satcatFile"`
+	SatcatHash string    `json:"satcatHash"` // hex SHA-256 of the SATCAT file
+	Filter     string    `json:"filter"`     // SatcatFilter applied to its rows
+	NextRow    int       `json:"nextRow"`    // first row not yet fetched
+	Updated    time.Time `json:"updated"`
+}
+
+// LoadProgress reads a progress file written by Progress.Save.
+func LoadProgress(filename string) (Progress, error) {
+	var p Progress
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return p, err
+	}
+	err = json.Unmarshal(data, &p)
+	return p, err
+}
+
+// Save writes the progress to filename, replacing it atomically so a crash
+// mid-write can't leave a truncated file behind.
+func (p Progress) Save(filename string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".progress-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// hashFile returns the hex SHA-256 of the file's contents.
+func hashFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// batchStats accumulates what TLE batch fetches have done, for progress
+// reports. It's safe for concurrent use.
+type batchStats struct {
+	skipped atomic.Int64 // satellites skipped because their file exists
+	written atomic.Int64 // satellites whose TLEs were written
+	bytes   atomic.Int64 // response bytes received
+	batches atomic.Int64 // batches received
+	latency atomic.Int64 // total time waiting for batches, in nanoseconds
+}
+
+// fetchStats counts the work of every FetchTLEsForSATCAT call.
+var fetchStats batchStats
+
+// meanLatency returns the average time a batch has taken to arrive.
+func (s *batchStats) meanLatency() time.Duration {
+	batches := s.batches.Load()
+	if batches == 0 {
+		return 0
+	}
+	return time.Duration(s.latency.Load() / batches)
+}
+
+// tickPeriod returns how often the fetch loop can really fetch, each tick
+// issuing concurrency requests: the fetch interval, unless batches take
+// longer to arrive or the rate limits allow fewer ticks than that.
+func tickPeriod(interval time.Duration, latency time.Duration, concurrency int, perMinute int, perHour int) time.Duration {
+	period := interval
+	if latency > period {
+		period = latency
+	}
+	if perMinute > 0 {
+		if p := time.Minute * time.Duration(concurrency) / time.Duration(perMinute); p > period {
+			period = p
+		}
+	}
+	if perHour > 0 {
+		if p := time.Hour * time.Duration(concurrency) / time.Duration(perHour); p > period {
+			period = p
+		}
+	}
+	return period
+}
+
+// reportProgress logs how far through total rows the fetch has got and
+// when it should finish, fetching rowsPerTick rows each period.
+func reportProgress(done int, total int, rowsPerTick int, period time.Duration) {
+	if done > total {
+		done = total
+	}
+	remaining := total - done
+	ticks := (remaining + rowsPerTick - 1) / rowsPerTick
+
+	slog.Info("Progress",
+		"done", done,
+		"remaining", remaining,
+		"percent", fmt.Sprintf("%.1f", 100*float64(done)/float64(max(total, 1))),
+		"skipped", fetchStats.skipped.Load(),
+		"bytes", fetchStats.bytes.Load(),
+		"eta", (time.Duration(ticks) * period).Round(time.Second))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deorbit/satfetch"
+)
+
+// stringList is a flag that can be given more than once, collecting each
+// value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// classQueryPath builds the path of a query for class from predicates of the
+// form NAME/value, such as INCLINATION/>98, adding orderby, limit and format
+// predicates for any that aren't empty.
+func classQueryPath(class string, where []string, orderBy string, limit string, format string) (string, error) {
+	var predicates []string
+	for _, w := range where {
+		name, value, ok := strings.Cut(w, "/")
+		if !ok || name == "" || value == "" {
+			return "", fmt.Errorf("bad predicate %q, want NAME/value, e.g. INCLINATION/>98", w)
+		}
+		predicates = append(predicates, name, value)
+	}
+	for _, p := range [][2]string{{"orderby", orderBy}, {"limit", limit}, {"format", format}} {
+		if p[1] != "" {
+			predicates = append(predicates, p[0], p[1])
+		}
+	}
+	if format != "" {
+		predicates = append(predicates, "metadata", "false")
+	}
+	return satfetch.QueryPath(class, predicates...)
+}
+
+// queryClass queries Space Track for class with predicates, alternating
+// names and values, as satfetch.QueryPath takes them.
+func queryClass(client *satfetch.Client, class string, predicates ...string) ([]byte, error) {
+	path, err := satfetch.QueryPath(class, predicates...)
+	if err != nil {
+		return nil, err
+	}
+	return client.Query(path)
+}
+
+// writeReport writes a subcommand's report to standard output: v as
+// indented JSON if format is json, and otherwise a table of header and
+// rows, with cells right-aligned if alignRight is set.
+func writeReport(format string, v any, alignRight bool, header []string, rows [][]string) error {
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	var flags uint
+	end := "\n"
+	if alignRight {
+		// tabwriter only aligns cells ended by a tab.
+		flags, end = tabwriter.AlignRight, "\t\n"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', flags)
+	for _, row := range append([][]string{header}, rows...) {
+		fmt.Fprint(w, strings.Join(row, "\t")+end)
+	}
+	return w.Flush()
+}
+
+// runQuery implements the query subcommand, which queries any Space Track
+// class and writes the raw response.
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	class := fs.String("class", "", "Space Track class to query, e.g. gp, gp_history, decay, boxscore,\n"+
+		"launch_site or cdm_public.")
+	var where stringList
+	fs.Var(&where, "where", "Predicate of the form NAME/value, e.g. INCLINATION/>98 or EPOCH/>now-1.\n"+
+		"May be repeated.")
+	orderBy := fs.String("orderby", "", "Sort order, e.g. \"EPOCH desc\".")
+	limit := fs.String("limit", "", "Max number of results, optionally followed by an offset, e.g. 100 or 100,200.")
+	format := fs.String("format", "json", "Response format: json, csv, xml, html, tle, 3le or kvn.")
+	out := fs.String("out", StdoutDir, "File to write the response to, gzip-compressed if it ends in .gz.\n"+
+		"Defaults to standard output.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	if *class == "" {
+		fatal("query needs -class")
+	}
+
+	path, err := classQueryPath(*class, where, *orderBy, *limit, *format)
+	if err != nil {
+		fatal(err)
+	}
+
+	client := satfetch.NewClientFromConfig(config)
+	if err := client.Login(); err != nil {
+		fatal(err)
+	}
+	resp, err := client.Query(path)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *out == StdoutDir {
+		if _, err := os.Stdout.Write(resp); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	slog.Info("Writing query response", "file", *out, "bytes", len(resp))
+	if err := satfetch.WriteFile(*out, resp, 0644); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/deorbit/satfetch"
+)
+
+// runResolve implements the resolve subcommand, printing the NORAD IDs of
+// the satellites in a SATCAT with a name. Each is followed by a # comment
+// of its name, designator and launch date, so the output can be given to
+// -norad-file as it is.
+func runResolve(args []string) {
+	fs := flag.NewFlagSet("resolve", flag.ExitOnError)
+	satcatFilename := fs.String("satcat", "", "SATCAT file to search. CSV, or JSON if the filename ends in .json.")
+	name := fs.String("name", "", "Name, or part of one, to look for, ignoring case, e.g. NOAA.")
+	exact := fs.Bool("exact", false, "Only match whole names.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	if *satcatFilename == "" || *name == "" {
+		fatal("resolve needs -satcat and -name")
+	}
+
+	satcatRows, err := loadSATCAT(*satcatFilename)
+	if err != nil {
+		fatal(err)
+	}
+	index := satfetch.NewSatcatIndex(satcatRows)
+	var matches []satfetch.SatcatRow
+	if *exact {
+		matches = index.ByExactName(*name)
+	} else {
+		matches = index.ByName(*name)
+	}
+	if len(matches) == 0 {
+		fatalf("No satellites in %s match %q", *satcatFilename, *name)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, row := range matches {
+		fmt.Fprintf(w, "%s\t# %s\t%s\t%s\n", row.NORADID, satcatName(row), row.IntlDes, row.LaunchDate)
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
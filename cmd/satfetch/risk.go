@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/deorbit/satfetch"
+)
+
+// runRisk implements the risk subcommand, listing the satellites in a TLE
+// directory most likely to reenter soon.
+func runRisk(args []string) {
+	fs := flag.NewFlagSet("risk", flag.ExitOnError)
+	satcatFilename := fs.String("satcat", "", "SATCAT file of the satellites to rank, skipping those already decayed,\n"+
+		"and to name them. CSV, or JSON if the filename ends in .json. Defaults to every\n"+
+		"satellite in -tle-dir.")
+	tleDir := fs.String("tle-dir", "./tle", "Directory of TLE files of the satellites to rank.")
+	top := fs.Int("top", 20, "Number of satellites to list. All if 0.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+
+	var tles []satfetch.TLE
+	names := make(map[uint64]string)
+	if *satcatFilename == "" {
+		var err error
+		if tles, err = loadTLEDir(*tleDir); err != nil {
+			fatal(err)
+		}
+	} else {
+		satcatRows, err := loadSATCAT(*satcatFilename)
+		if err != nil {
+			fatal(err)
+		}
+		for _, row := range satcatRows {
+			noradID, err := strconv.ParseUint(row.NORADID, 10, 64)
+			if err != nil || row.DecayDate != "" {
+				continue
+			}
+			data, err := readLocalTLEs(row.NORADID, *tleDir)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				fatal(err)
+			}
+			parsed, err := satfetch.ParseTLEText(data)
+			if err != nil {
+				fatalf("NORAD ID %s: %v", row.NORADID, err)
+			}
+			tles = append(tles, parsed...)
+			names[noradID] = satcatName(row)
+		}
+	}
+
+	ranked := satfetch.RankByDecayRisk(tles)
+	if *top > 0 && len(ranked) > *top {
+		ranked = ranked[:*top]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NORAD ID\tNAME\tPERIGEE KM\tBSTAR\tEPOCH\tRISK")
+	for _, tle := range ranked {
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%.4g\t%s\t%.3g\n", tle.NORADID, names[tle.NORADID],
+			tle.PerigeeKm(), tle.BSTAR, tle.EpochTime().Format("2006-01-02 15:04"), satfetch.DecayRisk(tle))
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
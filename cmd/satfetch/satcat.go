@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deorbit/satfetch"
+)
+
+// satcatFilterFlags registers the flags selecting SATCAT rows on fs,
+// returning a function that builds the SatcatFilter once fs is parsed.
+func satcatFilterFlags(fs *flag.FlagSet) func() satfetch.SatcatFilter {
+	objectType := fs.String("object-type", "", "Only fetch TLEs for these comma-separated SATCAT object types, e.g. PAYLOAD.")
+	country := fs.String("country", "", "Only fetch TLEs for objects owned by these comma-separated SATCAT country codes.")
+	onOrbit := fs.Bool("on-orbit", false, "Only fetch TLEs for objects that haven't decayed.")
+	maxPerigee := fs.Float64("max-perigee", 0, "Only fetch TLEs for objects with a perigee at or below this many km.")
+
+	return func() satfetch.SatcatFilter {
+		return satfetch.SatcatFilter{
+			ObjectType:  splitList(*objectType),
+			Country:     splitList(*country),
+			OnOrbitOnly: *onOrbit,
+			MaxPerigee:  *maxPerigee,
+		}
+	}
+}
+
+// readNORADFile reads a list of NORAD IDs, one per line, ignoring blank
+// lines, # comments and repeats. Lines that aren't valid catalog numbers are
+// returned in invalid, with their line numbers, rather than failing the read.
+func readNORADFile(filename string) (noradIDs []string, invalid []string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(line, 10, 32)
+		if err != nil || id == 0 {
+			invalid = append(invalid, fmt.Sprintf("line %d: %q", n, line))
+			continue
+		}
+		if noradID := strconv.FormatUint(id, 10); !seen[noradID] {
+			seen[noradID] = true
+			noradIDs = append(noradIDs, noradID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return noradIDs, invalid, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
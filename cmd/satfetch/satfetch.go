@@ -0,0 +1,1653 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// FetchSATCAT downloads the full satellite catalog from Space Track in the
+// given format, csv or json, and writes it to filename, compressed if the
+// name ends in .gz. The file is left alone if the download doesn't parse or
+// has no rows.
+func FetchSATCAT(client *satfetch.Client, format string, filename string) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unsupported SATCAT format %q", format)
+	}
+
+	resp, err := queryClass(client, "satcat",
+		"orderby", "LAUNCH asc",
+		"format", format,
+		"metadata", "false")
+	if err != nil {
+		return err
+	}
+
+	// Don't replace a good catalog with an error page or an empty one.
+	var satcatRows []satfetch.SatcatRow
+	if format == "json" {
+		satcatRows, err = satfetch.ParseSATCATJSON(bytes.NewReader(resp))
+	} else {
+		satcatRows, err = satfetch.ReadSATCATCSV(bytes.NewReader(resp))
+	}
+	if err != nil {
+		return fmt.Errorf("not writing %s: %v", filename, err)
+	}
+	if len(satcatRows) == 0 {
+		return fmt.Errorf("not writing %s: downloaded SATCAT has no rows", filename)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), dirMode()); err != nil {
+		return err
+	}
+	slog.Info("Writing SATCAT", "file", filename)
+	return satfetch.WriteFile(filename, resp, fileMode)
+}
+
+// FetchTLEs queries Space Track for all available two-line element sets for a
+// satellite with the given noradId, or those newer than -since asks for.
+func FetchTLEs(client *satfetch.Client, noradId string, destdir string) error {
+	if err := satfetch.CheckNORADIDs(noradId); err != nil {
+		return err
+	}
+	predicates, err := sincePredicates(destdir, []string{noradId})
+	if err != nil {
+		return err
+	}
+	path, err := tleQueryPath(tleClass, append([]string{"NORAD_CAT_ID", noradId}, predicates...)...)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Query(path)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTLEResponse(resp); err != nil {
+		return err
+	}
+
+	return writeTLEFile(destdir, noradId, resp)
+}
+
+// FetchTLEsByIntlDes queries Space Track for all available two-line element
+// sets for the objects with the given international designator, writing one
+// file per NORAD ID to destdir. A designator without a piece letter, such
+// as 1998-067, matches every object from that launch.
+func FetchTLEsByIntlDes(client *satfetch.Client, intlDes string, destdir string) error {
+	name, predicate, err := tleIntlDesPredicate(intlDes)
+	if err != nil {
+		return err
+	}
+
+	path, err := tleQueryPath(tleClass, name, predicate)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Query(path)
+	if err != nil {
+		return err
+	}
+
+	return writeTLEFiles(destdir, resp)
+}
+
+// FetchTLEsByNORADIDs queries Space Track for all available two-line element
+// sets for the given satellites in a single request, writing one file per
+// NORAD ID to destdir.
+func FetchTLEsByNORADIDs(client *satfetch.Client, noradIDs []string, destdir string) error {
+	if err := satfetch.CheckNORADIDs(noradIDs...); err != nil {
+		return err
+	}
+	path, err := tleQueryPath(tleClass, "NORAD_CAT_ID", strings.Join(noradIDs, ","))
+	if err != nil {
+		return err
+	}
+	resp, err := client.Query(path)
+	if err != nil {
+		return err
+	}
+
+	return writeTLEFiles(destdir, resp)
+}
+
+// tleIntlDesPredicate returns the predicate matching a designator like
+// 1998-067A in tleClass. The tle class stores it as 98067A under INTLDES,
+// the gp classes as is under OBJECT_ID. Designators without a piece become
+// a starts-with match.
+func tleIntlDesPredicate(intlDes string) (string, string, error) {
+	if len(intlDes) < 8 || intlDes[4] != '-' {
+		return "", "", fmt.Errorf("bad international designator %q, want e.g. 1998-067A", intlDes)
+	}
+
+	name, des := "OBJECT_ID", intlDes
+	if tleClass == "tle" {
+		name, des = "INTLDES", intlDes[2:4]+intlDes[5:]
+	}
+	if len(intlDes) == 8 {
+		return name, "^" + des, nil
+	}
+	return name, des, nil
+}
+
+// resolveIntlDes returns the NORAD IDs of the SATCAT rows whose designator
+// is intlDes or, if intlDes has no piece letter, starts with it.
+func resolveIntlDes(index *satfetch.SatcatIndex, intlDes string) []string {
+	var noradIDs []string
+	for _, row := range index.ByIntlDes(intlDes) {
+		noradIDs = append(noradIDs, row.NORADID)
+	}
+	return noradIDs
+}
+
+// spaceTrackTime formats t the way Space Track writes dates, in UTC.
+func spaceTrackTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// writeTLEFiles splits a response of TLEs for several satellites and writes
+// each satellite's element sets to its own file in destdir.
+func writeTLEFiles(destdir string, resp []byte) error {
+	if err := checkTLEResponse(resp); err != nil {
+		return err
+	}
+
+	byNORAD, err := splitResponseByNORAD(resp)
+	if err != nil {
+		return err
+	}
+
+	for noradID, tles := range byNORAD {
+		if err := writeTLEFile(destdir, strconv.Itoa(noradID), tles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitTLEsByNORAD groups the element sets in a TLE response by catalog
+// number. Lines that aren't part of a line 1/line 2 pair for the same
+// satellite, as a truncated response leaves, are logged and dropped, so
+// each satellite only gets whole element sets of its own. It fails if
+// that leaves none at all.
+func splitTLEsByNORAD(resp []byte) (map[int][]byte, error) {
+	// Element sets arrive as consecutive line 1/line 2 pairs. Drop blank
+	// lines so a stray one can't shift the pairing, and any \r ending a
+	// CRLF line so it can't end up in the columns.
+	var lines []string
+	for _, line := range strings.Split(string(resp), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	byNORAD := make(map[int][]byte)
+	for i := 0; i < len(lines); {
+		line1 := lines[i]
+		if !strings.HasPrefix(line1, "1 ") || i+1 == len(lines) || !strings.HasPrefix(lines[i+1], "2 ") {
+			slog.Warn("Dropping TLE line without its pair", "line", line1)
+			i++
+			continue
+		}
+		line2 := lines[i+1]
+		i += 2
+
+		noradID, err := tleLineCatalogNumber(line1)
+		if err == nil {
+			var line2ID int
+			if line2ID, err = tleLineCatalogNumber(line2); err == nil && line2ID != noradID {
+				err = fmt.Errorf("line 1 is for %d but line 2 for %d", noradID, line2ID)
+			}
+		}
+		if err != nil {
+			slog.Warn("Dropping mismatched TLE lines", "line1", line1, "line2", line2, "err", err)
+			continue
+		}
+		byNORAD[noradID] = append(byNORAD[noradID], line1+"\n"+line2+"\n"...)
+	}
+
+	if len(byNORAD) == 0 && len(lines) > 0 {
+		return nil, fmt.Errorf("response has %d TLE lines but no whole element sets", len(lines))
+	}
+	return byNORAD, nil
+}
+
+// tleLineCatalogNumber returns the satellite number in columns 3-7 of a TLE
+// line.
+func tleLineCatalogNumber(line string) (int, error) {
+	if len(line) < 7 {
+		return 0, fmt.Errorf("short TLE line %q", line)
+	}
+	return strconv.Atoi(strings.TrimSpace(line[2:7]))
+}
+
+// StdoutDir is the TLE directory name that means standard output.
+const StdoutDir = "-"
+
+// stdoutMu keeps concurrent fetches from interleaving their output.
+var stdoutMu sync.Mutex
+
+// writeTLEsToStdout writes each element set in tles to standard output in
+// three-line form, preceded by a "0 name" title line.
+func writeTLEsToStdout(name string, tles []byte) error {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+
+	w := bufio.NewWriter(os.Stdout)
+	// Title lines from -name-line give way to these.
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(tles), "\r\n"), "\n") {
+		if !strings.HasPrefix(line, "0 ") {
+			lines = append(lines, line)
+		}
+	}
+	for i := 0; i+1 < len(lines); i += 2 {
+		fmt.Fprintf(w, "0 %s\n%s\n%s\n", name, strings.TrimRight(lines[i], "\r"), strings.TrimRight(lines[i+1], "\r"))
+	}
+	return w.Flush()
+}
+
+// satcatName returns the name to give an object's TLEs, falling back to
+// its NORAD ID.
+func satcatName(row satfetch.SatcatRow) string {
+	switch {
+	case row.SatName != "":
+		return row.SatName
+	case row.ObjectName != "":
+		return row.ObjectName
+	}
+	return row.NORADID
+}
+
+// sinceEpoch, unless zero, limits TLE fetches to element sets with later
+// epochs. Set by the -since flag.
+var sinceEpoch time.Time
+
+// sinceAuto limits TLE fetches to element sets newer than those already in
+// the satellites' files. Set by -since auto.
+var sinceAuto bool
+
+// sincePredicates returns the query predicates limiting a fetch of the
+// noradIDs' element sets to those newer than sinceEpoch or, with sinceAuto,
+// than the newest of each satellite's already in destDir. A query covers
+// several satellites, so it asks for those newer than the oldest of these,
+// and for the full history if any satellite has no file yet.
+func sincePredicates(destDir string, noradIDs []string) ([]string, error) {
+	if !sinceAuto {
+		if sinceEpoch.IsZero() {
+			return nil, nil
+		}
+		return []string{"EPOCH", ">" + spaceTrackTime(sinceEpoch)}, nil
+	}
+
+	var since time.Time
+	for _, noradID := range noradIDs {
+		newest, err := newestEpochOnDisk(destDir, noradID)
+		if err != nil {
+			return nil, err
+		}
+		if newest.IsZero() {
+			return nil, nil
+		}
+		if since.IsZero() || newest.Before(since) {
+			since = newest
+		}
+	}
+	if since.IsZero() {
+		return nil, nil
+	}
+	return []string{"EPOCH", ">" + spaceTrackTime(since)}, nil
+}
+
+// newestEpochOnDisk returns the newest epoch in noradID's TLE file in
+// destDir, or its latest year's, or the zero time if it has none.
+func newestEpochOnDisk(destDir string, noradID string) (time.Time, error) {
+	filename := tleFilename(destDir, noradID)
+	if splitByYear {
+		years, _ := filepath.Glob(filepath.Join(tleYearDir(destDir, noradID), "[0-9][0-9][0-9][0-9]"+tleFileExt()+"*"))
+		if len(years) == 0 {
+			return time.Time{}, nil
+		}
+		sort.Strings(years)
+		filename = years[len(years)-1]
+	}
+
+	data, err := satfetch.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	if data, err = decodeTLEs(data); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", filename, err)
+	}
+	tles, err := satfetch.ParseTLEText(data)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", filename, err)
+	}
+	newest, ok := satfetch.LatestTLE(tles)
+	if !ok {
+		return time.Time{}, nil
+	}
+	return newest.EpochTime(), nil
+}
+
+// dryRun makes FetchTLEsForSATCAT log the queries it would make instead of
+// making them, leaving files alone. Set by the -dry-run flag.
+var dryRun bool
+
+// mergeTLEs makes TLE fetches merge into existing .tle files, rather than
+// replacing them or skipping the satellite. Set by the -merge flag.
+var mergeTLEs bool
+
+// fileMode is the permissions TLE and SATCAT files are written with. Set by
+// the -file-mode flag.
+var fileMode os.FileMode = 0644
+
+// dirMode returns the permissions to create directories for files with
+// fileMode: the same, plus search permission for whoever can read them.
+func dirMode() os.FileMode {
+	return fileMode | fileMode&0444>>2
+}
+
+// splitByYear makes TLE fetches write each satellite's element sets to a
+// directory of their own, one file per epoch year. Set by the -split-by-year
+// flag.
+var splitByYear bool
+
+// writeTLEFile writes tles to noradId's file in destdir, laid out and
+// formatted as the flags say, or to standard output if destdir is
+// StdoutDir. A file is only replaced once the checksums verify and the new
+// one is completely written.
+func writeTLEFile(destdir string, noradId string, tles []byte) error {
+	if destdir == StdoutDir {
+		tles, err := pruneTLEText(tles)
+		if err != nil {
+			return fmt.Errorf("NORAD ID %s: %v", noradId, err)
+		}
+		return writeTLEsToStdout(noradIDName(noradId), tles)
+	}
+
+	if tleFormat == "xml" {
+		return writeOMMFile(tleFilename(destdir, noradId), noradId, tles)
+	}
+	if !splitByYear {
+		filename := tleFilename(destdir, noradId)
+		if err := os.MkdirAll(filepath.Dir(filename), dirMode()); err != nil {
+			return err
+		}
+		return writeTLEFileNamed(filename, noradId, tles)
+	}
+
+	// Prune first so -latest-only and -max-age see the whole history, not
+	// a year at a time.
+	tles, err := pruneTLEText(tles)
+	if err != nil {
+		return fmt.Errorf("NORAD ID %s: %v", noradId, err)
+	}
+	byYear := make(map[int][]byte)
+	err = satfetch.IterateTLEs(bytes.NewReader(tles), func(tle satfetch.TLE) error {
+		year := tle.EpochTime().Year()
+		byYear[year] = append(byYear[year], tle.Text()...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("NORAD ID %s: %v", noradId, err)
+	}
+
+	if err := os.MkdirAll(tleYearDir(destdir, noradId), dirMode()); err != nil {
+		return err
+	}
+	for year, tles := range byYear {
+		if err := writeTLEFileNamed(tleYearFilename(destdir, noradId, year), noradId, tles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTLEFileNamed does the work of writeTLEFile for one file.
+func writeTLEFileNamed(filename string, noradId string, tles []byte) error {
+	if mergeTLEs {
+		existing, err := satfetch.ReadFile(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if existing, err = decodeTLEs(existing); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		if tles, err = mergeTLEText(existing, tles); err != nil {
+			return fmt.Errorf("merging into %s: %v", filename, err)
+		}
+	}
+	tles, err := pruneTLEText(tles)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	if err := satfetch.VerifyTLEText(tles); err != nil {
+		return fmt.Errorf("not writing %s: %v", filename, err)
+	}
+	data, err := encodeTLEs(tles)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	slog.Info("Writing TLEs", "file", filename)
+	if err := satfetch.WriteFile(filename, data, fileMode); err != nil {
+		return err
+	}
+	return recordManifestEntry(filename, noradId, tles, data)
+}
+
+// mergeTLEText returns the union of the element sets in existing and fresh,
+// sorted by epoch. Sets with the same NORAD ID and epoch appear once, taking
+// the text from fresh.
+func mergeTLEText(existing []byte, fresh []byte) ([]byte, error) {
+	var merged []satfetch.TLE
+	seen := make(map[[2]string]int)
+
+	for _, resp := range [][]byte{existing, fresh} {
+		tles, err := satfetch.ParseTLEText(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tle := range tles {
+			// Key on the epoch text so float rounding can't split duplicates.
+			key := [2]string{strconv.FormatUint(tle.NORADID, 10), tle.Text()[18:32]}
+			if j, ok := seen[key]; ok {
+				merged[j] = tle
+				continue
+			}
+			seen[key] = len(merged)
+			merged = append(merged, tle)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Epoch != merged[j].Epoch {
+			return merged[i].EpochTime().Before(merged[j].EpochTime())
+		}
+		return merged[i].NORADID < merged[j].NORADID
+	})
+
+	var buf bytes.Buffer
+	for _, tle := range merged {
+		buf.WriteString(tle.Text())
+	}
+	return buf.Bytes(), nil
+}
+
+// maxTLEAge, if positive, drops element sets with epochs longer ago than
+// this. Set by the -max-age flag.
+var maxTLEAge time.Duration
+
+// latestTLEOnly keeps just each satellite's newest element set. Set by the
+// -latest-only flag.
+var latestTLEOnly bool
+
+// newestFirst sorts written element sets newest first rather than oldest
+// first. Set by the -order flag.
+var newestFirst bool
+
+// pruneTLEText applies maxTLEAge and latestTLEOnly to TLE text, and sorts the
+// surviving element sets by epoch, per newestFirst, whatever order the
+// server sent them in.
+func pruneTLEText(tles []byte) ([]byte, error) {
+	parsed, err := satfetch.ParseTLEText(tles)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(parsed, func(i, j int) bool {
+		ti, tj := parsed[i].EpochTime(), parsed[j].EpochTime()
+		if ti.Equal(tj) {
+			return parsed[i].NORADID < parsed[j].NORADID
+		}
+		return ti.Before(tj) != newestFirst
+	})
+	checkElementSetNumbers(parsed, newestFirst)
+
+	var fresh []satfetch.TLE
+	for _, tle := range parsed {
+		if maxTLEAge > 0 && time.Since(tle.EpochTime()) > maxTLEAge {
+			continue
+		}
+		fresh = append(fresh, tle)
+	}
+
+	// Being sorted, each satellite's newest element set is its last, or its
+	// first when newest first.
+	latest := make(map[uint64]int)
+	for i, tle := range fresh {
+		if _, ok := latest[tle.NORADID]; !ok || !newestFirst {
+			latest[tle.NORADID] = i
+		}
+	}
+
+	var buf bytes.Buffer
+	for i, tle := range fresh {
+		if latestTLEOnly && latest[tle.NORADID] != i {
+			continue
+		}
+		if nameLine {
+			buf.WriteString("0 " + tleName(tle) + "\n")
+		}
+		buf.WriteString(tle.Text())
+	}
+	return buf.Bytes(), nil
+}
+
+// nameLine makes TLE files three-line, with each element set preceded by a
+// "0 name" title line. Set by the -name-line flag.
+var nameLine bool
+
+// tleNames is the SATCAT title lines take names from, if any.
+var tleNames *satfetch.SatcatIndex
+
+// tleName returns the name for tle's title line: the SATCAT's, or else the
+// one it was read with, or else its NORAD ID.
+func tleName(tle satfetch.TLE) string {
+	noradID := strconv.FormatUint(tle.NORADID, 10)
+	if tleNames != nil {
+		if row, ok := tleNames.ByNORAD(noradID); ok {
+			return satcatName(row)
+		}
+	}
+	if tle.Name != "" {
+		return tle.Name
+	}
+	return noradID
+}
+
+// noradIDName returns the name for the title lines of the satellite with
+// noradId: the SATCAT's, or else the NORAD ID.
+func noradIDName(noradId string) string {
+	if tleNames != nil {
+		if row, ok := tleNames.ByNORAD(noradId); ok {
+			return satcatName(row)
+		}
+	}
+	return noradId
+}
+
+// checkElementSetNumbers warns about each element set in tles, sorted by
+// epoch, whose element set number is lower than that of the same satellite's
+// one before it, as a sign of element sets out of order or mixed up. Going
+// from above 9000 to below 1000 is just the four digits wrapping.
+func checkElementSetNumbers(tles []satfetch.TLE, newestFirst bool) {
+	previous := make(map[uint64]satfetch.TLE)
+	for i := range tles {
+		tle := tles[i]
+		if newestFirst {
+			tle = tles[len(tles)-1-i]
+		}
+		prev, ok := previous[tle.NORADID]
+		previous[tle.NORADID] = tle
+		if !ok || tle.ElementSetNumber >= prev.ElementSetNumber ||
+			prev.ElementSetNumber > 9000 && tle.ElementSetNumber < 1000 {
+			continue
+		}
+		slog.Warn("Element set number went down", "norad", tle.NORADID,
+			"epoch", tle.EpochTime(), "number", tle.ElementSetNumber,
+			"previousEpoch", prev.EpochTime(), "previous", prev.ElementSetNumber)
+	}
+}
+
+// loadSATCAT parses a SATCAT file, as JSON if its name ends in .json and as
+// CSV otherwise, decompressing it first if its name ends in .gz. A catalog
+// without any rows is an error.
+func loadSATCAT(filename string) ([]satfetch.SatcatRow, error) {
+	var satcatRows []satfetch.SatcatRow
+	if !strings.HasSuffix(strings.TrimSuffix(filename, satfetch.GzipExt), ".json") {
+		var err error
+		if satcatRows, err = satfetch.ParseSATCATCSV(filename); err != nil {
+			return nil, err
+		}
+	} else {
+		file, err := satfetch.OpenFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		if satcatRows, err = satfetch.ParseSATCATJSON(file); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	if len(satcatRows) == 0 {
+		return nil, fmt.Errorf("%s: catalog contains no entries", filename)
+	}
+	return satcatRows, nil
+}
+
+// catalogAge returns how out of date the SATCAT in filename with rows
+// satcatRows looks: the time since the file was modified or since the
+// latest launch it lists, whichever is longer, as a copied file can be new
+// while its contents are not.
+func catalogAge(filename string, satcatRows []satfetch.SatcatRow) (time.Duration, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	age := time.Since(info.ModTime())
+
+	var latestLaunch string
+	for _, row := range satcatRows {
+		if row.LaunchDate > latestLaunch {
+			latestLaunch = row.LaunchDate
+		}
+	}
+	if launch, err := time.Parse(satfetch.SatcatDateLayout, latestLaunch); err == nil && time.Since(launch) > age {
+		age = time.Since(launch)
+	}
+	return age, nil
+}
+
+// FetchResult counts what became of the satellites in some SATCAT rows.
+type FetchResult struct {
+	Fetched int // satellites whose TLEs were written
+	Skipped int // satellites whose files already existed
+	NoData  int // satellites Space Track has no element sets for
+	Failed  int // satellites whose queries failed
+	NextRow int // the row to carry on from, at most the end of the catalog
+}
+
+// add accumulates o into r.
+func (r *FetchResult) add(o FetchResult) {
+	r.Fetched += o.Fetched
+	r.Skipped += o.Skipped
+	r.NoData += o.NoData
+	r.Failed += o.Failed
+	if o.NextRow > r.NextRow {
+		r.NextRow = o.NextRow
+	}
+}
+
+// FetchTLEsForSATCAT fetches the TLEs of the satellites in numToFetch rows
+// of satcatRows from startRow in one batch, writing them to destDir or, if
+// it is StdoutDir, to standard output. Satellites whose files exist are
+// skipped, and those missing from the response are asked for again one at
+// a time. Cancelling ctx abandons the request.
+func FetchTLEsForSATCAT(ctx context.Context, client *satfetch.Client, satcatRows []satfetch.SatcatRow, startRow int, numToFetch int, destDir string) (result FetchResult, err error) {
+	var noradIDQuery string
+	var noradIDs []int
+	names := make(map[int]string)
+
+	if startRow >= len(satcatRows) {
+		return FetchResult{NextRow: len(satcatRows)}, nil
+	}
+	endRow := startRow + numToFetch
+	if endRow > len(satcatRows) {
+		endRow = len(satcatRows)
+	}
+	result.NextRow = endRow
+
+	defer func() {
+		if err != nil {
+			result.Fetched, result.NoData = 0, 0
+			result.Failed = endRow - startRow - result.Skipped
+		}
+	}()
+
+	// Iterate over IDs, fetching batches of TLEs
+	for i, v := range satcatRows[startRow:endRow] {
+		slog.Debug("Preparing to fetch", "norad", v.NORADID)
+		if err := satfetch.CheckNORADIDs(v.NORADID); err != nil {
+			return result, fmt.Errorf("row %d: %v", startRow+i, err)
+		}
+		noradIDnumerical, err := strconv.Atoi(v.NORADID)
+		if err != nil {
+			return result, err
+		}
+
+		if destDir != StdoutDir && !mergeTLEs {
+			filename := tleFilename(destDir, v.NORADID)
+			if splitByYear {
+				filename = tleYearDir(destDir, v.NORADID)
+			}
+			_, err := os.Stat(filename)
+			switch {
+			case err == nil && dryRun:
+				slog.Info("Dry run: would skip existing file", "file", filename)
+				continue
+			case err == nil:
+				slog.Info(highlight(filename + " exists. Skipping that NORAD ID."))
+				fetchStats.skipped.Add(1)
+				result.Skipped++
+				continue
+			case !os.IsNotExist(err):
+				return result, err
+			}
+		}
+
+		// Add to the list of NORAD IDs we'll fetch
+		noradIDQuery += v.NORADID + ","
+		noradIDs = append(noradIDs, noradIDnumerical)
+		names[noradIDnumerical] = satcatName(v)
+	}
+
+	if noradIDQuery == "" {
+		return result, nil
+	}
+
+	noradIDQuery = noradIDQuery[:len(noradIDQuery)-1]
+	predicates, err := sincePredicates(destDir, strings.Split(noradIDQuery, ","))
+	if err != nil {
+		return result, err
+	}
+	path, err := tleQueryPath(tleClass, append([]string{"NORAD_CAT_ID", noradIDQuery}, predicates...)...)
+	if err != nil {
+		return result, err
+	}
+	if dryRun {
+		slog.Info("Dry run: would request batch", "first", startRow, "last", endRow-1,
+			"satellites", len(noradIDs), "url", client.APIRoot+path)
+		return result, nil
+	}
+
+	slog.Debug("Requesting batch", "path", path)
+	t0 := time.Now()
+	byNORAD, err := queryTLEsByNORAD(ctx, client, path)
+	batchFailed := err != nil
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, satfetch.ErrAuthFailed) || errors.Is(err, satfetch.ErrBudgetExhausted) {
+			return result, err
+		}
+		slog.Warn("Batch failed, fetching its satellites one at a time", "first", startRow, "err", err)
+		byNORAD = make(map[int][]byte)
+	} else {
+		slog.Info("Received batch", "satellites", len(noradIDs), "elapsed", time.Since(t0))
+	}
+
+	var noData []int
+	for _, noradID := range noradIDs {
+		if _, ok := byNORAD[noradID]; ok {
+			continue
+		}
+		// Asking only for new element sets, most satellites are expected to
+		// be missing from the response.
+		if len(predicates) > 0 && !batchFailed {
+			noData = append(noData, noradID)
+			continue
+		}
+		predicates, err := sincePredicates(destDir, []string{strconv.Itoa(noradID)})
+		if err != nil {
+			return result, err
+		}
+		path, err := tleQueryPath(tleClass, append([]string{"NORAD_CAT_ID", strconv.Itoa(noradID)}, predicates...)...)
+		if err != nil {
+			return result, err
+		}
+		single, err := queryTLEsByNORAD(ctx, client, path)
+		switch {
+		case err != nil && (ctx.Err() != nil || errors.Is(err, satfetch.ErrAuthFailed) || errors.Is(err, satfetch.ErrBudgetExhausted)):
+			return result, err
+		case err != nil:
+			slog.Warn("Fetching TLEs failed", "norad", noradID, "err", err)
+			result.Failed++
+		case len(single[noradID]) == 0:
+			noData = append(noData, noradID)
+		default:
+			byNORAD[noradID] = single[noradID]
+		}
+	}
+	switch {
+	case len(noData) > 0 && len(predicates) > 0:
+		slog.Info("Space Track has no new TLEs for these NORAD IDs", "norad", noData)
+	case len(noData) > 0:
+		slog.Warn("Space Track has no TLEs for these NORAD IDs", "norad", noData)
+	}
+	result.NoData = len(noData)
+	// Counted back as failed if anything below goes wrong.
+	result.Fetched = len(noradIDs) - len(noData) - result.Failed
+
+	if destDir == StdoutDir {
+		for _, noradID := range noradIDs {
+			tles, ok := byNORAD[noradID]
+			if !ok {
+				continue
+			}
+			if tles, err = pruneTLEText(tles); err != nil {
+				return result, fmt.Errorf("NORAD ID %d: %v", noradID, err)
+			}
+			if err := writeTLEsToStdout(names[noradID], tles); err != nil {
+				return result, err
+			}
+			fetchStats.written.Add(1)
+		}
+		return result, nil
+	}
+
+	for _, noradID := range noradIDs {
+		if tles, ok := byNORAD[noradID]; ok {
+			if err := writeTLEFile(destDir, strconv.Itoa(noradID), tles); err != nil {
+				return result, err
+			}
+			fetchStats.written.Add(1)
+		}
+	}
+	return result, nil
+}
+
+// queryTLEsByNORAD runs a TLE query, checks that the response is element
+// sets and groups them by NORAD ID.
+func queryTLEsByNORAD(ctx context.Context, client *satfetch.Client, path string) (map[int][]byte, error) {
+	t0 := time.Now()
+	resp, err := client.QueryContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(t0)
+	fetchStats.batches.Add(1)
+	fetchStats.bytes.Add(int64(len(resp)))
+	fetchStats.latency.Add(int64(elapsed))
+
+	if err := checkTLEResponse(resp); err != nil {
+		return nil, err
+	}
+	return splitResponseByNORAD(resp)
+}
+
+// FetchTLEBatches fetches TLEs for satcatRows[startRow:endRow] with
+// FetchTLEsForSATCAT, batchSize rows per request, using up to concurrency
+// workers at once. The workers share client and so its rate limits. Errors
+// from individual batches are joined and returned once all have finished,
+// along with the batches' results added up. Cancelling ctx stops new batches
+// from starting and abandons the requests under way.
+func FetchTLEBatches(ctx context.Context, client *satfetch.Client, satcatRows []satfetch.SatcatRow, startRow int, endRow int,
+	batchSize int, concurrency int, destDir string) (FetchResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	batches := make(chan int)
+	errs := make(chan error)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := FetchResult{NextRow: startRow}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batchStart := range batches {
+				n := batchSize
+				if batchStart+n > endRow {
+					n = endRow - batchStart
+				}
+				result, err := FetchTLEsForSATCAT(ctx, client, satcatRows, batchStart, n, destDir)
+				mu.Lock()
+				total.add(result)
+				mu.Unlock()
+				if err != nil {
+					errs <- fmt.Errorf("batch at row %d: %w", batchStart, err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for batchStart := startRow; batchStart < endRow && ctx.Err() == nil; batchStart += batchSize {
+			batches <- batchStart
+		}
+		close(batches)
+		wg.Wait()
+		close(errs)
+	}()
+
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return total, errors.Join(all...)
+}
+
+// fetchFromSource writes the current TLE for a single satellite from src to
+// destDir.
+func fetchFromSource(src satfetch.TLESource, noradID string, destDir string) error {
+	resp, err := src.FetchTLE(noradID)
+	if err != nil {
+		return err
+	}
+	return writeTLEFile(destDir, noradID, resp)
+}
+
+// fetchOneSatellite writes the TLEs for a single satellite to destDir,
+// limited to the epochs between the RFC3339 times start and end if either
+// is given.
+func fetchOneSatellite(client *satfetch.Client, noradID string, start string, end string, destDir string) error {
+	if start == "" && end == "" {
+		return FetchTLEs(client, noradID, destDir)
+	}
+
+	// The first TLEs date from 1957; an open end means up to now.
+	startTime := time.Date(1957, time.January, 1, 0, 0, 0, 0, time.UTC)
+	endTime := time.Now()
+	var err error
+	if start != "" {
+		if startTime, err = time.Parse(time.RFC3339, start); err != nil {
+			return fmt.Errorf("bad -epoch-start: %v", err)
+		}
+	}
+	if end != "" {
+		if endTime, err = time.Parse(time.RFC3339, end); err != nil {
+			return fmt.Errorf("bad -epoch-end: %v", err)
+		}
+	}
+
+	if err := satfetch.CheckNORADIDs(noradID); err != nil {
+		return err
+	}
+	// Space Track's range operator is "--"; a comma would mean "or".
+	path, err := tleQueryPath(tleHistoryClass(),
+		"NORAD_CAT_ID", noradID,
+		"EPOCH", spaceTrackTime(startTime)+"--"+spaceTrackTime(endTime))
+	if err != nil {
+		return err
+	}
+	resp, err := client.Query(path)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTLEResponse(resp); err != nil {
+		return err
+	}
+	return writeTLEFile(destDir, noradID, resp)
+}
+
+// shutdownGrace is how long an interrupted run has to finish the batches
+// under way before it quits regardless.
+const shutdownGrace = 30 * time.Second
+
+// handleInterrupts calls cancel on the first SIGINT or SIGTERM, so that the
+// batches under way can finish writing or remove their partial files. A
+// second signal, or shutdownGrace passing, exits at once.
+func handleInterrupts(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		slog.Info("Quitting once the batches under way are done. Interrupt again to quit now.")
+		cancel()
+
+		select {
+		case <-signals:
+			slog.Warn("Quitting now")
+		case <-time.After(shutdownGrace):
+			slog.Warn("Batches didn't finish in time, quitting", "grace", shutdownGrace)
+		}
+		os.Exit(1)
+	}()
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "look":
+			runLook(os.Args[2:])
+			return
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "decay":
+			runDecay(os.Args[2:])
+			return
+		case "conjunctions":
+			runConjunctions(os.Args[2:])
+			return
+		case "latest":
+			runLatest(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "boxscore":
+			runBoxscore(os.Args[2:])
+			return
+		case "overhead":
+			runOverhead(os.Args[2:])
+			return
+		case "filter":
+			runFilter(os.Args[2:])
+			return
+		case "resolve":
+			runResolve(os.Args[2:])
+			return
+		case "risk":
+			runRisk(os.Args[2:])
+			return
+		case "czml":
+			runCZML(os.Args[2:])
+			return
+		case "sync":
+			runSync(os.Args[2:])
+			return
+		}
+	}
+
+	if err := run(); err != nil {
+		fatal(err)
+	}
+}
+
+// run is the command without a subcommand, fetching the SATCAT and TLEs.
+// It returns errors rather than exiting, so that the lock is released and
+// the manifest written on the way out.
+func run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handleInterrupts(cancel)
+	// triggerTLEFetch stays nil, never firing, while waiting out -watch.
+	var triggerTLEFetch <-chan time.Time
+	lastFetched := 0
+	satcatRows := make([]satfetch.SatcatRow, 0)
+
+	versionFlag := flag.Bool("v", false, "Print version number.")
+	fetchTLEs := flag.Bool("tle", false, "Fetch Space Track TLEs for satellites listed in the specified satcat.")
+	tleDir := flag.String("tle-dir", "./tle", "Directory where TLEs are stored, one file per NORAD ID.\n"+
+		"Use - to write them to standard output instead, each titled with a \"0 NAME\" line.")
+	batchSize := flag.Int("batch-size", 5, "Max number of NORAD IDs to fetch per TLE request.")
+	concurrency := flag.Int("concurrency", 1, "Number of TLE batches to fetch at once.")
+	fetchInterval := flag.Duration("fetch-interval", time.Minute, "Time between TLE batch requests.")
+	perMinute := flag.Int("rate-per-minute", satfetch.DefaultRequestsPerMinute, "Max Space Track requests per minute. 0 for no limit.")
+	perHour := flag.Int("rate-per-hour", satfetch.DefaultRequestsPerHour, "Max Space Track requests per hour. 0 for no limit.")
+	logLevel := flag.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	maxRequests := flag.Int("max-requests", 0, "Stop after this many Space Track queries, saving progress to resume from.\n"+
+		"0 for no limit.")
+	maxSatellites := flag.Int("max-satellites", 0, "Stop after fetching TLEs for this many satellites, not counting ones skipped\n"+
+		"for already having files, saving progress to resume from. 0 for no limit.")
+	cacheDir := flag.String("cache-dir", "", "Directory to keep Space Track responses in, reusing them for the same queries.")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "With -cache-dir, how long a kept response is reused for. 0 for ever.")
+	maxAttempts := flag.Int("max-attempts", satfetch.DefaultMaxAttempts, "Max tries per Space Track query on transient errors.")
+	satcatFilename := flag.String("satcat", "", "SATCAT file to use for other operations.\n"+
+		"CSV, or JSON if the filename ends in .json.")
+	fetchSatcat := flag.Bool("fetch-satcat", false, "Download the Space Track SATCAT and use it for other operations.")
+	satcatFormat := flag.String("format", "csv", "Format of the SATCAT to download: csv or json.")
+	satcatOut := flag.String("satcat-out", "", "File to write the downloaded SATCAT to. Defaults to satcat.csv or satcat.json,\n"+
+		"with .gz added with -gzip.")
+	force := flag.Bool("force", false, "Let -fetch-satcat replace an existing SATCAT file.")
+	maxCatalogAge := flag.Duration("max-catalog-age", 30*24*time.Hour, "Warn if the SATCAT was downloaded, or last lists a launch, longer ago than this.\n"+
+		"0 to not check.")
+	refreshCatalog := flag.Bool("refresh-catalog", false, "Download the -satcat file again first if it's older than -max-catalog-age.")
+	progressFile := flag.String("progress", "", "File recording how far the TLE fetch has got.\n"+
+		"Defaults to progress.json in the TLE directory.")
+	resume := flag.Bool("resume", false, "Resume the TLE fetch from the saved progress if the SATCAT is unchanged.")
+	restart := flag.Bool("restart", false, "Discard saved progress and fetch TLEs from the start of the SATCAT.")
+	noradFile := flag.String("norad-file", "", "Fetch TLEs, as -tle does, for just the NORAD IDs listed one per line in this file.\n"+
+		"Blank lines and # comments are ignored. No SATCAT needed, though names and filters\n"+
+		"are taken from one if given.")
+	noradID := flag.String("norad", "", "Fetch TLEs for just this NORAD ID into the TLE directory. No SATCAT needed.")
+	epochStart := flag.String("epoch-start", "", "With -norad, only fetch element sets with epochs from this RFC3339 time.")
+	epochEnd := flag.String("epoch-end", "", "With -norad, only fetch element sets with epochs up to this RFC3339 time.")
+	satcatFilterFromFlags := satcatFilterFlags(flag.CommandLine)
+	source := flag.String("source", "spacetrack", "Where -norad fetches TLEs from: spacetrack, for the full history,\n"+
+		"or celestrak, for just the current element set without a Space Track account.")
+	merge := flag.Bool("merge", false, "Merge fetched TLEs into existing files, dropping duplicate epochs and sorting\n"+
+		"by epoch, instead of replacing single-satellite files and skipping SATCAT ones.")
+	maxAge := flag.Duration("max-age", 0, "Drop fetched element sets with epochs longer ago than this, e.g. 336h.\n"+
+		"0 keeps them all.")
+	order := flag.String("order", "asc", "Order to write each satellite's element sets in by epoch: asc, oldest first,\n"+
+		"or desc, newest first.")
+	since := flag.String("since", "", "Only fetch element sets with epochs after this date or RFC3339 time, or with auto,\n"+
+		"after the newest already in each satellite's file. Implies -merge.")
+	offline := flag.Bool("offline", false, "Answer Space Track queries from the -fixtures directory instead of the network,\n"+
+		"without credentials or rate limits. Record fixtures by running once with -cache-dir.")
+	fixtures := flag.String("fixtures", "testdata", "With -offline, the directory of recorded query responses.")
+	watch := flag.Duration("watch", 0, "With -tle, keep running once every row is fetched, fetching them all again\n"+
+		"after this long, for use as a daemon. Implies -merge. Off if 0.")
+	nameLineFlag := flag.Bool("name-line", false, "Write TLE files in three-line form, with a \"0 name\" title line before each\n"+
+		"element set naming the object as the SATCAT does.")
+	var extra stringList
+	flag.Var(&extra, "query-extra", "Predicates of the form NAME/value[/NAME/value...] to add to TLE fetches' queries,\n"+
+		"e.g. MEAN_MOTION/>11.25, or orderby/EPOCH desc to change the order. Values are\n"+
+		"path-escaped, so give them unescaped. May be repeated.")
+	splitByYearFlag := flag.Bool("split-by-year", false, "Write each satellite's TLEs to <norad>/<year>.tle in the TLE directory,\n"+
+		"by epoch, instead of one <norad>.tle file.")
+	layout := flag.String("output-layout", "flat", "Layout of the TLE directory: flat, with every satellite's files in it, or\n"+
+		"sharded, with them in subdirectories by the first two digits of the five-digit\n"+
+		"catalog number, e.g. 25/25544.tle, for archives too big to list quickly. Reading\n"+
+		"commands find files in either.")
+	latestOnly := flag.Bool("latest-only", false, "Keep only each satellite's newest element set instead of its full history.")
+	dryRunFlag := flag.Bool("dry-run", false, "With -tle, log the batches and query URLs a fetch would use,\n"+
+		"without querying Space Track or writing files.")
+	quiet := flag.Bool("quiet", false, "Don't log progress and the expected finish time after each TLE fetch.")
+	tleFormatFlag := flag.String("tle-format", "text", "Format of the TLE files: text, for <norad>.tle files of two-line element sets,\n"+
+		"json, for <norad>.json files holding an array of the parsed element sets, or xml, for\n"+
+		"<norad>.xml files of the CCSDS OMMs Space Track sends from the gp classes, as sent.")
+	fields := flag.String("fields", "", "With -tle-format json, write only these comma-separated fields of each element set,\n"+
+		"e.g. noradid,epoch,inclination,meanMotion. Files written so can't be merged into.")
+	class := flag.String("class", "gp_history", "Space Track class to fetch TLEs from: gp_history, every element set,\n"+
+		"gp, for just each object's current one, or tle, the deprecated history.")
+	gzipFlag := flag.Bool("gzip", false, "Write the SATCAT and TLE files gzip-compressed, adding .gz to their names.\n"+
+		"Files ending in .gz are always decompressed when read.")
+	intlDes := flag.String("intldes", "", "Fetch TLEs for the objects with this international designator, e.g. 1998-067A,\n"+
+		"or for every object from a launch, e.g. 1998-067.")
+
+	startupJitter := flag.Bool("startup-jitter", false, "Wait a random part of -fetch-interval before the first request, so runs started\n"+
+		"together, e.g. by cron, don't make their requests at the same moments.")
+	lockFile := flag.String("lock-file", "", "File to lock for the run, so that another satfetch given the same file won't\n"+
+		"run alongside this one. A lock left by a process that's gone is taken over.")
+	fileModeFlag := flag.String("file-mode", "0644", "Octal permissions to write TLE and SATCAT files with, e.g. 0640 for group-readable\n"+
+		"archives. Directories made for them get search permission where there's read.")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics at /metrics on, e.g. :9100. None if empty.")
+	resolveConfig := credentialFlags(flag.CommandLine)
+
+	flag.Parse()
+	if *versionFlag {
+		fmt.Println("satfetch v0.1")
+		return nil
+	}
+	if err := setupLogging(*logLevel); err != nil {
+		return err
+	}
+	if *lockFile != "" {
+		release, err := acquireLock(*lockFile)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		return err
+	}
+	var opts []satfetch.ClientOption
+	if *offline {
+		opts = append(opts, satfetch.WithTransport(fixtureTransport{dir: *fixtures}))
+	}
+	client := satfetch.NewClientFromConfig(config, opts...)
+	// Each tick, every worker fetches one batch.
+	rowsPerTick := *batchSize * *concurrency
+	if *noradFile != "" {
+		*fetchTLEs = true
+	}
+	client.SetRateLimits(*perMinute, *perHour)
+	if *offline {
+		client.SetRateLimits(0, 0)
+	}
+	client.MaxAttempts = *maxAttempts
+	client.MaxRequests = *maxRequests
+	client.CacheDir = *cacheDir
+	client.CacheTTL = *cacheTTL
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr, client)
+	}
+	mergeTLEs = *merge || *watch > 0
+	nameLine = *nameLineFlag
+	for _, fragment := range extra {
+		predicates, err := parseQueryExtra(fragment)
+		if err != nil {
+			return err
+		}
+		queryExtra = append(queryExtra, predicates...)
+	}
+	switch *since {
+	case "":
+	case "auto":
+		sinceAuto = true
+		mergeTLEs = true
+	default:
+		var err error
+		if sinceEpoch, err = time.Parse(time.RFC3339, *since); err != nil {
+			if sinceEpoch, err = time.Parse(satfetch.SatcatDateLayout, *since); err != nil {
+				return fmt.Errorf("Bad -since %q, want auto, a date such as 2024-01-01 or an RFC3339 time", *since)
+			}
+		}
+		mergeTLEs = true
+	}
+	maxTLEAge = *maxAge
+	latestTLEOnly = *latestOnly
+	splitByYear = *splitByYearFlag
+	outputLayout = *layout
+	if outputLayout != "flat" && outputLayout != "sharded" {
+		return fmt.Errorf("Unknown -output-layout %q, want flat or sharded", outputLayout)
+	}
+	if mode, err := strconv.ParseUint(*fileModeFlag, 8, 32); err != nil || mode > 0777 {
+		return fmt.Errorf("Bad -file-mode %q, want octal permissions such as 0644", *fileModeFlag)
+	} else {
+		fileMode = os.FileMode(mode)
+	}
+	switch *order {
+	case "asc":
+	case "desc":
+		newestFirst = true
+	default:
+		return fmt.Errorf("Unknown -order %q, want asc or desc", *order)
+	}
+	dryRun = *dryRunFlag
+	gzipOutput = *gzipFlag
+	tleFormat = *tleFormatFlag
+	tleClass = *class
+	client.TLEClass = tleClass
+
+	switch tleClass {
+	case "tle", "gp", "gp_history":
+	default:
+		return fmt.Errorf("Unknown -class %q, want tle, gp or gp_history", tleClass)
+	}
+	if tleFormat != "text" && tleFormat != "json" && tleFormat != "xml" {
+		return fmt.Errorf("Unknown -tle-format %q, want text, json or xml", tleFormat)
+	}
+	if tleFormat == "xml" {
+		if tleClass == "tle" {
+			return errors.New("-tle-format xml needs -class gp or gp_history, which Space Track sends OMMs from")
+		}
+		// The files hold what Space Track sent, not element sets satfetch
+		// can rewrite.
+		if mergeTLEs || latestTLEOnly || maxTLEAge > 0 || newestFirst || splitByYear || nameLine || *source != "spacetrack" {
+			return errors.New("-tle-format xml can't be used with -merge, -since, -watch, -latest-only, -max-age, -order desc, -split-by-year, -name-line or -source celestrak")
+		}
+	}
+	if *fields != "" {
+		tleFields = splitList(*fields)
+		if err := checkTLEFields(tleFields); err != nil {
+			return err
+		}
+		if tleFormat != "json" {
+			return errors.New("-fields needs -tle-format json")
+		}
+		if mergeTLEs {
+			return errors.New("-fields and -merge can't be used together")
+		}
+	}
+	if tleFormat != "text" && *tleDir == StdoutDir {
+		return fmt.Errorf("-tle-format %s can't be written to standard output", tleFormat)
+	}
+
+	// singleFetch is set when we've been asked for particular satellites,
+	// which doesn't need a SATCAT.
+	singleFetch := *noradID != "" || *intlDes != ""
+
+	if *refreshCatalog && *satcatFilename != "" && !*fetchSatcat {
+		if *maxCatalogAge <= 0 {
+			return errors.New("-refresh-catalog needs -max-catalog-age")
+		}
+		rows, err := loadSATCAT(*satcatFilename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		age, err := catalogAge(*satcatFilename, rows)
+		if os.IsNotExist(err) || err == nil && age > *maxCatalogAge {
+			slog.Info("Refreshing SATCAT", "file", *satcatFilename, "age", age.Round(time.Hour))
+			*fetchSatcat = true
+			*force = true
+			*satcatOut = *satcatFilename
+			*satcatFormat = "csv"
+			if strings.HasSuffix(strings.TrimSuffix(*satcatFilename, satfetch.GzipExt), ".json") {
+				*satcatFormat = "json"
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if *fetchSatcat {
+		if *satcatOut == "" {
+			*satcatOut = "satcat." + *satcatFormat
+			if gzipOutput {
+				*satcatOut += satfetch.GzipExt
+			}
+		}
+		// Check before logging in: the download is too big to throw away.
+		if _, err := os.Stat(*satcatOut); err == nil && !*force {
+			return fmt.Errorf("%s exists. Use -force to download the SATCAT again.", *satcatOut)
+		}
+	}
+
+	if *startupJitter && !dryRun {
+		jitter := time.Duration(rand.Int63n(int64(*fetchInterval) + 1))
+		slog.Info("Waiting before the first request", "jitter", jitter.Round(time.Second))
+		select {
+		case <-time.After(jitter):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	// Update the manifest with whatever gets written, however the run ends.
+	if *tleDir != StdoutDir {
+		defer func() {
+			if err := writeManifest(*tleDir); err != nil {
+				slog.Warn("Writing manifest failed", "err", err)
+			}
+		}()
+	}
+
+	if dryRun && (*fetchSatcat || singleFetch) {
+		return errors.New("-dry-run only works with -tle and a local SATCAT")
+	}
+
+	switch *source {
+	case "spacetrack":
+		if (*fetchSatcat || *fetchTLEs || singleFetch) && !dryRun {
+			if err := client.Login(); err != nil {
+				return err
+			}
+		}
+	case "celestrak":
+		if *noradID == "" || *fetchSatcat || *fetchTLEs || *intlDes != "" || *epochStart != "" || *epochEnd != "" {
+			return errors.New("-source celestrak only supports -norad without an epoch range")
+		}
+	default:
+		return fmt.Errorf("Unknown -source %q, want spacetrack or celestrak", *source)
+	}
+
+	if *fetchSatcat {
+		if err := FetchSATCAT(client, *satcatFormat, *satcatOut); err != nil {
+			return err
+		}
+		*satcatFilename = *satcatOut
+	}
+
+	if *satcatFilename == "" {
+		if !singleFetch && *noradFile == "" {
+			return errors.New("Dude, where's my SATCAT at?")
+		}
+	} else {
+		var err error
+		satcatRows, err = loadSATCAT(*satcatFilename)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("Loaded SATCAT", "entries", len(satcatRows),
+			"first", satcatRows[0].NORADID,
+			"last", satcatRows[len(satcatRows)-1].NORADID)
+
+		if *maxCatalogAge > 0 {
+			age, err := catalogAge(*satcatFilename, satcatRows)
+			if err != nil {
+				return err
+			}
+			if age > *maxCatalogAge {
+				slog.Warn("SATCAT is out of date, so may be missing recent launches and decays. Use -refresh-catalog to download it again.",
+					"file", *satcatFilename, "age", age.Round(time.Hour))
+			}
+		}
+	}
+	satcatIndex := satfetch.NewSatcatIndex(satcatRows)
+	tleNames = satcatIndex
+
+	// Fetched after the SATCAT is loaded, to name its TLEs from.
+	if *noradID != "" {
+		var err error
+		if *source == "celestrak" {
+			err = fetchFromSource(satfetch.NewCelestrakSource(), *noradID, *tleDir)
+		} else {
+			err = fetchOneSatellite(client, *noradID, *epochStart, *epochEnd, *tleDir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// rowsFile is where the rows to fetch came from, for saving progress.
+	rowsFile := *satcatFilename
+	if *noradFile != "" {
+		noradIDs, invalid, err := readNORADFile(*noradFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range invalid {
+			slog.Warn("Ignoring invalid NORAD ID", "file", *noradFile, "at", line)
+		}
+
+		// Rows the SATCAT doesn't know are left with just their NORAD IDs.
+		satcatRows = make([]satfetch.SatcatRow, len(noradIDs))
+		for i, id := range noradIDs {
+			if row, ok := satcatIndex.ByNORAD(id); ok {
+				satcatRows[i] = row
+			} else {
+				satcatRows[i] = satfetch.SatcatRow{NORADID: id}
+			}
+		}
+		rowsFile = *noradFile
+		slog.Info("Loaded NORAD IDs", "file", *noradFile, "entries", len(satcatRows))
+	}
+
+	if *intlDes != "" {
+		// Resolve the designator with the SATCAT if we have one, otherwise
+		// leave it to Space Track.
+		var err error
+		if noradIDs := resolveIntlDes(satcatIndex, *intlDes); len(noradIDs) > 0 {
+			err = FetchTLEsByNORADIDs(client, noradIDs, *tleDir)
+		} else {
+			err = FetchTLEsByIntlDes(client, *intlDes, *tleDir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if singleFetch && !*fetchTLEs {
+		return nil
+	}
+
+	satcatFilter := satcatFilterFromFlags()
+	if satcatFilter.String() != "" {
+		satcatRows = satfetch.FilterSatcat(satcatRows, satcatFilter)
+		slog.Info("Filtered SATCAT", "entries", len(satcatRows), "filter", satcatFilter.String())
+	}
+
+	var satcatHash string
+	if *fetchTLEs {
+		if *progressFile == "" && *tleDir == StdoutDir {
+			*progressFile = "progress.json"
+		} else if *progressFile == "" {
+			*progressFile = filepath.Join(*tleDir, "progress.json")
+		}
+		var err error
+		satcatHash, err = hashFile(rowsFile)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case *resume && *restart:
+			return errors.New("-resume and -restart can't be used together")
+		case *restart && !dryRun:
+			if err := os.Remove(*progressFile); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		case *resume:
+			progress, err := LoadProgress(*progressFile)
+			switch {
+			case os.IsNotExist(err):
+				slog.Info("No saved progress, starting from the first row")
+			case err != nil:
+				return err
+			case progress.SatcatHash != satcatHash:
+				slog.Info("SATCAT has changed since the last run, starting from the first row", "file", rowsFile)
+			case progress.Filter != satcatFilter.String():
+				slog.Info("SATCAT filter has changed since the last run, starting from the first row")
+			default:
+				lastFetched = progress.NextRow
+				slog.Info("Resuming", "row", lastFetched)
+			}
+		}
+	}
+
+	if dryRun {
+		// Run through every batch at once; nothing is fetched so there's no
+		// need to pace them.
+		if *fetchTLEs {
+			if _, err := FetchTLEBatches(ctx, client, satcatRows, lastFetched, len(satcatRows),
+				*batchSize, 1, *tleDir); err != nil {
+				return err
+			}
+
+			// The first tick fires immediately, then one per interval.
+			rows := len(satcatRows) - lastFetched
+			if rows < 0 {
+				rows = 0
+			}
+			ticks := (rows + rowsPerTick - 1) / rowsPerTick
+			var duration time.Duration
+			if ticks > 1 {
+				duration = time.Duration(ticks-1) * *fetchInterval
+			}
+			slog.Info("Dry run done", "rows", rows,
+				"maxRequests", (rows+*batchSize-1) / *batchSize, "duration", duration)
+		}
+		return nil
+	}
+
+	// fetchNextBatches fetches the next rowsPerTick rows. Progress is saved
+	// only up to the first failure, so a resumed run retries from there.
+	progressStalled := false
+	var fetched FetchResult
+	fetchNextBatches := func() error {
+		endRow := lastFetched + rowsPerTick
+		// Each row is at most one satellite, so this can't overshoot
+		// -max-satellites.
+		if left := *maxSatellites - fetched.Fetched - fetched.NoData; *maxSatellites > 0 && lastFetched+left < endRow {
+			endRow = lastFetched + left
+		}
+		result, err := FetchTLEBatches(ctx, client, satcatRows, lastFetched, endRow,
+			*batchSize, *concurrency, *tleDir)
+		fetched.add(result)
+		lastFetched = result.NextRow
+		switch {
+		case errors.Is(err, satfetch.ErrBudgetExhausted):
+			// Stop as an interrupt would.
+			slog.Warn("Used up -max-requests, stopping. Run again with -resume to carry on.", "requests", *maxRequests)
+			cancel()
+		case *maxSatellites > 0 && fetched.Fetched+fetched.NoData >= *maxSatellites && lastFetched < len(satcatRows):
+			slog.Info("Fetched -max-satellites, stopping. Run again with -resume to carry on.",
+				"satellites", *maxSatellites, "row", lastFetched)
+			cancel()
+		}
+		if !*quiet {
+			period := tickPeriod(*fetchInterval, fetchStats.meanLatency(), *concurrency, *perMinute, *perHour)
+			reportProgress(lastFetched, len(satcatRows), rowsPerTick, period)
+		}
+		if err != nil {
+			progressStalled = true
+			return err
+		}
+		if progressStalled {
+			return nil
+		}
+
+		progress := Progress{
+			SatcatFile: rowsFile,
+			SatcatHash: satcatHash,
+			Filter:     satcatFilter.String(),
+			NextRow:    lastFetched,
+			Updated:    time.Now().UTC(),
+		}
+		if err := progress.Save(*progressFile); err != nil {
+			slog.Warn("Saving progress failed", "err", err)
+		}
+		return nil
+	}
+
+	// finished reports whether every row has been fetched, logging the
+	// run's totals if so.
+	finished := func() bool {
+		if lastFetched < len(satcatRows) {
+			return false
+		}
+		slog.Info("Fetched TLEs for every SATCAT row", "fetched", fetched.Fetched,
+			"skipped", fetched.Skipped, "noData", fetched.NoData, "failed", fetched.Failed)
+		if fetched.Failed > 0 {
+			slog.Warn("Some batches failed. Run again with -resume to retry them.")
+		}
+		return true
+	}
+
+	// over reports whether the run is done, having fetched every row or
+	// been stopped. With -watch, having fetched every row, it starts over
+	// from the first once -watch has passed instead.
+	var nextRound <-chan time.Time
+	over := func() bool {
+		if ctx.Err() != nil {
+			return true
+		}
+		if !finished() {
+			return false
+		}
+		if *watch <= 0 {
+			return true
+		}
+		if *tleDir != StdoutDir {
+			if err := writeManifest(*tleDir); err != nil {
+				slog.Warn("Writing manifest failed", "err", err)
+			}
+		}
+		slog.Info("Watching, fetching again later", "in", *watch)
+		lastFetched = 0
+		fetched = FetchResult{}
+		progressStalled = false
+		triggerTLEFetch = nil
+		nextRound = time.After(*watch)
+		return false
+	}
+
+	// Without -tle, the work is done; only TLE fetches need waiting for.
+	if !*fetchTLEs {
+		return nil
+	}
+
+	slog.Debug("Gonna fetch some TLEs for you.")
+	if err := fetchNextBatches(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	if over() {
+		return nil
+	}
+
+	ticker := time.NewTicker(*fetchInterval)
+	defer ticker.Stop()
+	if nextRound == nil {
+		triggerTLEFetch = ticker.C
+	}
+
+	for {
+		select {
+		case <-nextRound:
+			nextRound = nil
+			ticker.Reset(*fetchInterval)
+			triggerTLEFetch = ticker.C
+			if err := fetchNextBatches(); err != nil && ctx.Err() == nil {
+				slog.Error("Fetching TLEs failed", "row", 0, "err", err)
+			}
+			if over() {
+				return nil
+			}
+		case <-triggerTLEFetch:
+			// Set TLE fetch trigger, spacing requests out so we don't hammer Space Track
+			from := lastFetched
+			if err := fetchNextBatches(); err != nil && ctx.Err() == nil {
+				slog.Error("Fetching TLEs failed", "row", from, "err", err)
+			}
+			if over() {
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
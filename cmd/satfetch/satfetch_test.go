@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/deorbit/satfetch"
+)
+
+// Element sets the test server knows, by NORAD ID.
+var testTLEs = map[string]string{
+	"5": "1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753\n" +
+		"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667\n",
+	"25544": "1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927\n" +
+		"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537\n",
+	"28129": "1 28129U 03058A   06175.57071136 -.00000104  00000-0  10000-3 0   459\n" +
+		"2 28129  54.7298 324.8098 0048506 266.2640  93.1663  2.00562768 18443\n",
+}
+
+// newTLEServer returns a client of a server answering TLE queries with the
+// testTLEs of the NORAD IDs asked for, counting the queries in n.
+func newTLEServer(t *testing.T, n *atomic.Int32) *satfetch.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		parts := strings.Split(r.URL.Path, "/")
+		for i, part := range parts {
+			if part == "NORAD_CAT_ID" && i+1 < len(parts) {
+				for _, id := range strings.Split(parts[i+1], ",") {
+					w.Write([]byte(testTLEs[id]))
+				}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client := satfetch.NewClient(srv.URL+"/ajaxauth/login", srv.URL+"/basicspacedata", "user", "pass")
+	client.SetRateLimits(0, 0)
+	return client
+}
+
+func TestFetchTLEsForSATCAT(t *testing.T) {
+	rows := []satfetch.SatcatRow{{NORADID: "5"}, {NORADID: "25544"}, {NORADID: "28129"}}
+	var n atomic.Int32
+	client := newTLEServer(t, &n)
+	dir := t.TempDir()
+
+	// Two rows at a time leaves a last batch of one.
+	var total FetchResult
+	batches := 0
+	for row := 0; row < len(rows); batches++ {
+		if batches > len(rows) {
+			t.Fatalf("still fetching after %d batches", batches)
+		}
+		result, err := FetchTLEsForSATCAT(context.Background(), client, rows, row, 2, dir)
+		if err != nil {
+			t.Fatalf("FetchTLEsForSATCAT from row %d: %v", row, err)
+		}
+		if result.NextRow <= row || result.NextRow > len(rows) {
+			t.Fatalf("FetchTLEsForSATCAT from row %d: NextRow = %d", row, result.NextRow)
+		}
+		total.add(result)
+		row = result.NextRow
+	}
+	if batches != 2 || n.Load() != 2 {
+		t.Errorf("fetched in %d batches with %d queries, want 2 of each", batches, n.Load())
+	}
+	if total.Fetched != 3 || total.Failed != 0 || total.NoData != 0 || total.NextRow != 3 {
+		t.Errorf("results add up to %+v, want all 3 fetched", total)
+	}
+	for _, row := range rows {
+		data, err := os.ReadFile(filepath.Join(dir, row.NORADID+".tle"))
+		if err != nil {
+			t.Error(err)
+		} else if string(data) != testTLEs[row.NORADID] {
+			t.Errorf("%s.tle =\n%s\nwant\n%s", row.NORADID, data, testTLEs[row.NORADID])
+		}
+	}
+
+	// Past the end of the catalog, there's nothing to do.
+	for _, startRow := range []int{len(rows), len(rows) + 5} {
+		result, err := FetchTLEsForSATCAT(context.Background(), client, rows, startRow, 2, dir)
+		if err != nil || result != (FetchResult{NextRow: len(rows)}) {
+			t.Errorf("FetchTLEsForSATCAT from row %d = %+v, %v, want nothing done", startRow, result, err)
+		}
+	}
+	if n.Load() != 2 {
+		t.Errorf("made %d queries in all, want no more than the 2 batches", n.Load())
+	}
+}
+
+// newResponseServer returns a client of a server answering every query
+// with resp.
+func newResponseServer(t *testing.T, resp string) *satfetch.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	client := satfetch.NewClient(srv.URL+"/ajaxauth/login", srv.URL+"/basicspacedata", "user", "pass")
+	client.SetRateLimits(0, 0)
+	return client
+}
+
+func TestFetchTLEsForSATCATSplit(t *testing.T) {
+	iss2 := "1 25544U 98067A   08265.51782528 -.00002182  00000-0 -11606-4 0  2939\n" +
+		"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563548\n"
+	rows := []satfetch.SatcatRow{{NORADID: "5"}, {NORADID: "25544"}}
+
+	// A batch of two satellites, one with two element sets, and a stray
+	// blank line.
+	dir := t.TempDir()
+	client := newResponseServer(t, testTLEs["25544"]+"\n"+testTLEs["5"]+iss2)
+	if _, err := FetchTLEsForSATCAT(context.Background(), client, rows, 0, 2, dir); err != nil {
+		t.Fatalf("FetchTLEsForSATCAT: %v", err)
+	}
+	want := map[string]string{
+		"5":     testTLEs["5"],
+		"25544": testTLEs["25544"] + iss2,
+	}
+	for id, tles := range want {
+		data, err := os.ReadFile(filepath.Join(dir, id+".tle"))
+		if err != nil {
+			t.Error(err)
+		} else if string(data) != tles {
+			t.Errorf("%s.tle =\n%s\nwant\n%s", id, data, tles)
+		}
+	}
+
+	// A line without its pair is dropped, leaving its satellite without
+	// data.
+	lines := strings.SplitAfter(testTLEs["5"], "\n")
+	client = newResponseServer(t, testTLEs["25544"]+lines[0])
+	result, err := FetchTLEsForSATCAT(context.Background(), client, rows, 0, 2, t.TempDir())
+	if err != nil || result.Fetched != 1 || result.NoData != 1 || result.Failed != 0 {
+		t.Errorf("FetchTLEsForSATCAT of a response with a lone line 1 = %+v, %v, want 1 fetched and 1 without data", result, err)
+	}
+}
+
+func TestSplitTLEsByNORAD(t *testing.T) {
+	iss2 := "1 25544U 98067A   08265.51782528 -.00002182  00000-0 -11606-4 0  2939\n" +
+		"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563548\n"
+	lines := func(tles string) []string { return strings.Split(strings.TrimSuffix(tles, "\n"), "\n") }
+
+	// A batch of two satellites, one with two element sets, then a line 1
+	// of one satellite paired with line 2 of another.
+	resp := testTLEs["25544"] + testTLEs["5"] + iss2 +
+		lines(testTLEs["28129"])[0] + "\n" + lines(testTLEs["5"])[1] + "\n"
+
+	byNORAD, err := splitTLEsByNORAD([]byte(resp))
+	if err != nil {
+		t.Fatalf("splitTLEsByNORAD: %v", err)
+	}
+	want := map[int]string{
+		5:     testTLEs["5"],
+		25544: testTLEs["25544"] + iss2,
+	}
+	if len(byNORAD) != len(want) {
+		t.Errorf("split into %d satellites, want %d", len(byNORAD), len(want))
+	}
+	for id, tles := range want {
+		if string(byNORAD[id]) != tles {
+			t.Errorf("NORAD ID %d =\n%s\nwant\n%s", id, byNORAD[id], tles)
+		}
+	}
+
+	// Nothing whole is an error; nothing at all isn't.
+	if _, err := splitTLEsByNORAD([]byte(lines(testTLEs["28129"])[0] + "\n")); err == nil {
+		t.Error("splitTLEsByNORAD of a lone line 1 succeeded")
+	}
+	if byNORAD, err := splitTLEsByNORAD(nil); err != nil || len(byNORAD) != 0 {
+		t.Errorf("splitTLEsByNORAD of nothing = %v, %v, want nothing", byNORAD, err)
+	}
+}
+
+// failingDoer fails the test if it's asked to send anything.
+type failingDoer struct{ t *testing.T }
+
+func (d failingDoer) Do(req *http.Request) (*http.Response, error) {
+	d.t.Errorf("sent %s %s, want nothing sent", req.Method, req.URL)
+	return nil, errors.New("no requests allowed")
+}
+
+func TestFetchMalformedNORADIDs(t *testing.T) {
+	client := satfetch.NewClient("https://example.com/ajaxauth/login", "https://example.com/basicspacedata", "user", "pass")
+	client.HTTPClient = failingDoer{t}
+	dir := t.TempDir()
+
+	if err := FetchTLEs(client, "25544/format/html", dir); err == nil {
+		t.Error("FetchTLEs with a malformed ID succeeded")
+	}
+	if err := FetchTLEsByNORADIDs(client, []string{"25544", "5/format/html"}, dir); err == nil {
+		t.Error("FetchTLEsByNORADIDs with a malformed ID succeeded")
+	}
+	if err := FetchLatestTLEs(client, []string{"25544", " 5"}, 10, dir); err == nil {
+		t.Error("FetchLatestTLEs with a malformed ID succeeded")
+	}
+	if err := fetchOneSatellite(client, "25544,5", "2024-01-01T00:00:00Z", "", dir); err == nil {
+		t.Error("fetchOneSatellite with a malformed ID succeeded")
+	}
+	rows := []satfetch.SatcatRow{{NORADID: "25544"}, {NORADID: "5 OR 1=1"}}
+	if _, err := FetchTLEsForSATCAT(context.Background(), client, rows, 0, 2, dir); err == nil {
+		t.Error("FetchTLEsForSATCAT with a malformed ID succeeded")
+	}
+}
+
+func TestSplitTLEsByNORADCRLF(t *testing.T) {
+	resp := testTLEs["25544"] + testTLEs["28129"]
+	byNORAD, err := splitTLEsByNORAD([]byte(strings.ReplaceAll(resp, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("splitTLEsByNORAD: %v", err)
+	}
+	for _, id := range []int{25544, 28129} {
+		if want := testTLEs[strconv.Itoa(id)]; string(byNORAD[id]) != want {
+			t.Errorf("NORAD ID %d from CRLF text = %q, want %q", id, byNORAD[id], want)
+		}
+	}
+}
+
+func TestSplitOMMsByNORAD(t *testing.T) {
+	resp, err := os.ReadFile("../../testdata/gp.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byNORAD, err := splitOMMsByNORAD(resp)
+	if err != nil {
+		t.Fatalf("splitOMMsByNORAD: %v", err)
+	}
+	want := map[int]string{25544: "ISS (ZARYA)", 28129: "NAVSTAR 54 (USA 175)"}
+	if len(byNORAD) != len(want) {
+		t.Errorf("split into %d satellites, want %d", len(byNORAD), len(want))
+	}
+	for id, name := range want {
+		// Each satellite's document holds its own OMM as sent, with the
+		// fields satfetch doesn't decode, and nothing of the other's.
+		doc := string(byNORAD[id])
+		if !strings.HasPrefix(doc, "<?xml") || !strings.HasSuffix(doc, "</ndm>\n") {
+			t.Errorf("NORAD ID %d isn't a whole ndm document:\n%s", id, doc)
+		}
+		if !strings.Contains(doc, `<USER_DEFINED parameter="PERIOD">`) {
+			t.Errorf("NORAD ID %d lost its user-defined parameters:\n%s", id, doc)
+		}
+		tles, err := satfetch.ParseOMM(strings.NewReader(doc))
+		if err != nil || len(tles) != 1 || tles[0].Name != name || tles[0].NORADID != uint64(id) {
+			t.Errorf("NORAD ID %d decodes to %+v, %v, want one element set of %s", id, tles, err, name)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// runSync implements the sync subcommand, which brings a directory up to
+// date with the current SATCAT, as satcat.csv, and the current element set
+// of each object still on orbit, one file each in tle/. Satellites whose
+// files are newer than the SATCAT were synced already, so an interrupted
+// sync carries on where it stopped when run again.
+func runSync(args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	out := fs.String("out", "./data", "Directory to sync.")
+	maxSatcatAge := fs.Duration("max-satcat-age", 24*time.Hour, "Download the SATCAT again if the one in -out is older than this.\n"+
+		"Every satellite's TLEs are fetched again when it is.")
+	batchSize := fs.Int("batch-size", 100, "Max number of NORAD IDs to fetch per request.")
+	logLevel := fs.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	resolveConfig := credentialFlags(fs)
+	fs.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+	config, err := resolveConfig()
+	if err != nil {
+		fatal(err)
+	}
+	satcatFilename := filepath.Join(*out, "satcat.csv")
+	tleDir := filepath.Join(*out, "tle")
+
+	client := satfetch.NewClientFromConfig(config)
+	loggedIn := false
+	login := func() {
+		if !loggedIn {
+			if err := client.Login(); err != nil {
+				fatal(err)
+			}
+			loggedIn = true
+		}
+	}
+
+	satcatInfo, err := os.Stat(satcatFilename)
+	if err != nil || time.Since(satcatInfo.ModTime()) > *maxSatcatAge {
+		login()
+		if err := FetchSATCAT(client, "csv", satcatFilename); err != nil {
+			fatal(err)
+		}
+		if satcatInfo, err = os.Stat(satcatFilename); err != nil {
+			fatal(err)
+		}
+	}
+	satcatRows, err := loadSATCAT(satcatFilename)
+	if err != nil {
+		fatal(err)
+	}
+	satcatRows = satfetch.FilterSatcat(satcatRows, satfetch.SatcatFilter{OnOrbitOnly: true})
+	tleNames = satfetch.NewSatcatIndex(satcatRows)
+
+	var noradIDs []string
+	for _, row := range satcatRows {
+		info, err := os.Stat(tleFilename(tleDir, row.NORADID))
+		if err == nil && info.ModTime().After(satcatInfo.ModTime()) {
+			continue
+		}
+		noradIDs = append(noradIDs, row.NORADID)
+	}
+	slog.Info("Syncing TLEs", "onOrbit", len(satcatRows), "synced", len(satcatRows)-len(noradIDs), "toFetch", len(noradIDs))
+	if len(noradIDs) == 0 {
+		return
+	}
+
+	login()
+	tleClass = "gp"
+	err = FetchLatestTLEs(client, noradIDs, *batchSize, tleDir)
+	if err := writeManifest(tleDir); err != nil {
+		slog.Warn("Writing manifest failed", "err", err)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
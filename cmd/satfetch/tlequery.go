@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deorbit/satfetch"
+)
+
+// tleClass is the Space Track class element sets are fetched from:
+// gp_history, the full history, gp, which holds just each object's current
+// element set, or tle, the deprecated history gp_history replaced. Set by
+// the -class flag.
+var tleClass = "gp_history"
+
+// tleHistoryClass returns the class holding past element sets as well as
+// current ones, for queries by epoch.
+func tleHistoryClass() string {
+	if tleClass == "gp" {
+		return "gp_history"
+	}
+	return tleClass
+}
+
+// queryExtra holds predicates, in name, value pairs, added to every TLE
+// fetch's query, including perhaps an orderby to use instead of oldest
+// first. Set by the -query-extra flag.
+var queryExtra []string
+
+// parseQueryExtra splits a path fragment of the form NAME/value[/NAME/value...],
+// e.g. MEAN_MOTION/>11.25/orderby/EPOCH desc, into name, value pairs for
+// queryExtra. The values are path-escaped when the query is built, so they're
+// given unescaped. The predicates satfetch needs to do its job, those picking
+// the satellites and the format, can't be given.
+func parseQueryExtra(fragment string) ([]string, error) {
+	parts := strings.Split(strings.Trim(fragment, "/"), "/")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("bad query predicates %q, want NAME/value pairs, e.g. MEAN_MOTION/>11.25", fragment)
+	}
+	for i := 0; i < len(parts); i += 2 {
+		name, value := parts[i], parts[i+1]
+		if name == "" || value == "" || strings.Trim(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+			return nil, fmt.Errorf("bad query predicate %q", name+"/"+value)
+		}
+		switch strings.ToLower(name) {
+		case "norad_cat_id", "format", "metadata", "class":
+			return nil, fmt.Errorf("query predicate %s can't be changed", name)
+		}
+	}
+	return parts, nil
+}
+
+// tleQueryPath builds the path of a query of class for element sets matching
+// the predicates and queryExtra, oldest first unless queryExtra orders them
+// otherwise, in tleQueryFormat.
+func tleQueryPath(class string, predicates ...string) (string, error) {
+	orderBy := "EPOCH asc"
+	for i := 0; i < len(queryExtra); i += 2 {
+		if strings.EqualFold(queryExtra[i], "orderby") {
+			orderBy = queryExtra[i+1]
+			continue
+		}
+		predicates = append(predicates, queryExtra[i], queryExtra[i+1])
+	}
+	return satfetch.QueryPath(class, append(predicates,
+		"orderby", orderBy,
+		"format", tleQueryFormat(),
+		"metadata", "false")...)
+}
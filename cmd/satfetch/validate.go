@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+// validateTLEFile checks a .tle or .tle.gz file written by a fetch, returning what's
+// wrong with it, if anything: no element sets, a missing line, lines that
+// don't parse, including line pairs for different satellites, or fail their
+// checksums, element sets for a satellite other than the one the file is
+// named for, epochs outside the year a file split by year is named for,
+// implausible elements, as reported by TLE.Sanity, and repeated epochs.
+// With a positive maxGap, it also reports runs of longer than that between
+// consecutive epochs, which suggest fetches that failed.
+func validateTLEFile(filename string, maxGap time.Duration) ([]string, error) {
+	data, err := satfetch.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Title lines, as -name-line writes, aren't checked.
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" && !strings.HasPrefix(line, "0 ") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return []string{"empty"}, nil
+	}
+
+	var problems []string
+	if len(lines)%2 != 0 {
+		problems = append(problems, fmt.Sprintf("truncated: %d lines, want pairs", len(lines)))
+	}
+
+	// Files split by year are named for the year, in a directory named for
+	// the satellite. Sharded files are named for the satellite, in their
+	// shardDir.
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filename), satfetch.GzipExt), ".tle")
+	dir := filepath.Base(filepath.Dir(filename))
+	wantYear := 0
+	if dirID, err := strconv.ParseUint(dir, 10, 64); err == nil && len(name) == 4 && shardDir(name) != dir {
+		if year, err := strconv.Atoi(name); err == nil {
+			wantYear = year
+			name = strconv.FormatUint(dirID, 10)
+		}
+	}
+	wantID, idErr := strconv.ParseUint(name, 10, 64)
+	epochs := make(map[string]int)
+	var times []time.Time
+	for i := 0; i+1 < len(lines); i += 2 {
+		tle, err := satfetch.ParseTLEStrict(lines[i], lines[i+1])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", i+1, err))
+			continue
+		}
+		if idErr == nil && tle.NORADID != wantID {
+			problems = append(problems, fmt.Sprintf("line %d: element set is for %d", i+1, tle.NORADID))
+		}
+		if year := tle.EpochTime().Year(); wantYear != 0 && year != wantYear {
+			problems = append(problems, fmt.Sprintf("line %d: epoch is in %d", i+1, year))
+		}
+		for _, warning := range tle.Sanity() {
+			problems = append(problems, fmt.Sprintf("line %d: implausible: %s", i+1, warning))
+		}
+		times = append(times, tle.EpochTime())
+
+		epoch := lines[i][18:32]
+		if first, ok := epochs[epoch]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: epoch %s repeats line %d", i+1, epoch, first))
+		} else {
+			epochs[epoch] = i + 1
+		}
+	}
+
+	if maxGap > 0 {
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		for i := 1; i < len(times); i++ {
+			if gap := times[i].Sub(times[i-1]); gap > maxGap {
+				problems = append(problems, fmt.Sprintf("gap of %s from %s to %s", gap.Round(time.Hour),
+					times[i-1].Format(time.RFC3339), times[i].Format(time.RFC3339)))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// runValidate implements the validate subcommand, checking every .tle and
+// .tle.gz file in a directory and exiting non-zero if any are bad.
+func runValidate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	tleDir := flags.String("tle-dir", "./tle", "Directory of .tle files to check.")
+	maxGap := flags.Duration("max-gap", 0, "Also report gaps longer than this between a satellite's epochs, e.g. 72h.\n"+
+		"Files split by year are checked a year at a time.")
+	logLevel := flags.String("log-level", "info", "Log verbosity: debug, info, warn or error.")
+	flags.Parse(args)
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal(err)
+	}
+
+	good, bad := 0, 0
+	err := filepath.WalkDir(*tleDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.TrimSuffix(path, satfetch.GzipExt), ".tle") {
+			return nil
+		}
+
+		problems, err := validateTLEFile(path, *maxGap)
+		if err != nil {
+			return err
+		}
+		if len(problems) == 0 {
+			good++
+			return nil
+		}
+		bad++
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+		return nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("%d good, %d bad\n", good, bad)
+	if bad > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,187 @@
+package satfetch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Space Track's endpoints, used unless configured otherwise.
+const (
+	DefaultLoginURL = "https://www.space-track.org/ajaxauth/login"
+	DefaultAPIRoot  = "https://www.space-track.org/basicspacedata"
+)
+
+// DefaultConfigFile is the config file read, if it exists, when none is
+// named, relative to the home directory.
+const DefaultConfigFile = ".satfetch.toml"
+
+// Config is how to reach and log in to Space Track.
+type Config struct {
+	Identity string
+	Password string
+	LoginURL string
+	APIRoot  string
+
+	// Accounts, if any, are spread queries over instead, see WithAccounts.
+	Accounts []Account
+}
+
+// Account is a Space Track user name and password.
+type Account struct {
+	Identity string
+	Password string
+}
+
+// accountKeys maps the keys of a config file's [[accounts]] sections to
+// Account fields.
+var accountKeys = map[string]func(*Account) *string{
+	"identity": func(a *Account) *string { return &a.Identity },
+	"password": func(a *Account) *string { return &a.Password },
+}
+
+// configKeys maps config file keys to Config fields.
+var configKeys = map[string]func(*Config) *string{
+	"identity":  func(c *Config) *string { return &c.Identity },
+	"password":  func(c *Config) *string { return &c.Password },
+	"login_url": func(c *Config) *string { return &c.LoginURL },
+	"api_root":  func(c *Config) *string { return &c.APIRoot },
+}
+
+// LoadConfig reads a config file. It understands the subset of TOML a
+// Config needs: comments, blank lines and key = "string" pairs, with the
+// keys identity, password, login_url and api_root, followed by any number of
+// [[accounts]] sections, each with an identity and password, e.g.
+//
+//	[[accounts]]
+//	identity = "ops1@example.com"
+//	password = "..."
+func LoadConfig(filename string) (Config, error) {
+	var c Config
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if line != "[[accounts]]" {
+				return c, fmt.Errorf("%s:%d: unknown section %s, want [[accounts]]", filename, n, line)
+			}
+			c.Accounts = append(c.Accounts, Account{})
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return c, fmt.Errorf("%s:%d: want key = \"value\"", filename, n)
+		}
+		key := strings.TrimSpace(line[:eq])
+		var field *string
+		if len(c.Accounts) > 0 {
+			if accountField, ok := accountKeys[key]; ok {
+				field = accountField(&c.Accounts[len(c.Accounts)-1])
+			}
+		} else if configField, ok := configKeys[key]; ok {
+			field = configField(&c)
+		}
+		if field == nil {
+			return c, fmt.Errorf("%s:%d: unknown key %q", filename, n, key)
+		}
+		value, err := strconv.Unquote(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return c, fmt.Errorf("%s:%d: %s must be a quoted string", filename, n, key)
+		}
+		*field = value
+	}
+	if err := scanner.Err(); err != nil {
+		return c, err
+	}
+
+	for i, account := range c.Accounts {
+		if account.Identity == "" || account.Password == "" {
+			return c, fmt.Errorf("%s: account %d needs an identity and a password", filename, i+1)
+		}
+	}
+	return c, nil
+}
+
+// configFromEnv reads the SPACETRACK* environment variables.
+func configFromEnv() Config {
+	return Config{
+		Identity: os.Getenv("SPACETRACKUSER"),
+		Password: os.Getenv("SPACETRACKPASS"),
+		LoginURL: os.Getenv("SPACETRACKLOGINURL"),
+		APIRoot:  os.Getenv("SPACETRACKAPIROOT"),
+	}
+}
+
+// override returns c with the fields that are set in o replaced.
+func (c Config) override(o Config) Config {
+	for _, field := range configKeys {
+		if v := *field(&o); v != "" {
+			*field(&c) = v
+		}
+	}
+	if len(o.Accounts) > 0 {
+		c.Accounts = o.Accounts
+	}
+	return c
+}
+
+// ResolveConfig combines the credential sources, each taking precedence
+// over the next: identity and the contents of passwordFile, then the config
+// file, then the environment, then the default endpoints. An empty
+// configFile means DefaultConfigFile in the home directory, if it exists.
+func ResolveConfig(configFile string, identity string, passwordFile string) (Config, error) {
+	c := Config{LoginURL: DefaultLoginURL, APIRoot: DefaultAPIRoot}.override(configFromEnv())
+
+	if configFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if _, err := os.Stat(filepath.Join(home, DefaultConfigFile)); err == nil {
+				configFile = filepath.Join(home, DefaultConfigFile)
+			}
+		}
+	}
+	if configFile != "" {
+		fileConfig, err := LoadConfig(configFile)
+		if err != nil {
+			return c, err
+		}
+		c = c.override(fileConfig)
+	}
+
+	flagConfig := Config{Identity: identity}
+	if passwordFile != "" {
+		password, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return c, err
+		}
+		flagConfig.Password = strings.TrimRight(string(password), "\r\n")
+		if flagConfig.Password == "" {
+			return c, errors.New(passwordFile + " is empty")
+		}
+	}
+	return c.override(flagConfig), nil
+}
+
+// NewClientFromConfig returns a Client for the Space Track described by c,
+// with opts applied, spreading its queries over c's accounts if it has any.
+func NewClientFromConfig(c Config, opts ...ClientOption) *Client {
+	if len(c.Accounts) > 0 {
+		opts = append(opts, WithAccounts(c.Accounts...))
+	}
+	return NewClient(c.LoginURL, c.APIRoot, c.Identity, c.Password, opts...)
+}
@@ -0,0 +1,146 @@
+package satfetch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setConfigEnv points the home directory at an empty temporary directory
+// and sets the SPACETRACK* variables to env, so that ResolveConfig sees
+// only what a test gives it.
+func setConfigEnv(t *testing.T, env map[string]string) {
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range []string{"SPACETRACKUSER", "SPACETRACKPASS", "SPACETRACKLOGINURL", "SPACETRACKAPIROOT"} {
+		t.Setenv(name, env[name])
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	env := map[string]string{
+		"SPACETRACKUSER":     "env-user",
+		"SPACETRACKPASS":     "env-pass",
+		"SPACETRACKLOGINURL": "https://env.example/login",
+	}
+	configFile := writeTemp(t, "satfetch.toml", `# Space Track
+identity = "file-user"
+
+api_root = "https://file.example/api"
+`)
+	passwordFile := writeTemp(t, "password", "flag-pass\n")
+
+	tests := []struct {
+		name                   string
+		env                    map[string]string
+		configFile             string
+		identity, passwordFile string
+		want                   Config
+	}{
+		{
+			name: "defaults",
+			want: Config{LoginURL: DefaultLoginURL, APIRoot: DefaultAPIRoot},
+		},
+		{
+			name: "environment over defaults",
+			env:  env,
+			want: Config{Identity: "env-user", Password: "env-pass", LoginURL: "https://env.example/login", APIRoot: DefaultAPIRoot},
+		},
+		{
+			name:       "file over environment",
+			env:        env,
+			configFile: configFile,
+			want:       Config{Identity: "file-user", Password: "env-pass", LoginURL: "https://env.example/login", APIRoot: "https://file.example/api"},
+		},
+		{
+			name:         "flags over file",
+			env:          env,
+			configFile:   configFile,
+			identity:     "flag-user",
+			passwordFile: passwordFile,
+			want:         Config{Identity: "flag-user", Password: "flag-pass", LoginURL: "https://env.example/login", APIRoot: "https://file.example/api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfigEnv(t, tt.env)
+			got, err := ResolveConfig(tt.configFile, tt.identity, tt.passwordFile)
+			if err != nil {
+				t.Fatalf("ResolveConfig: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveConfig =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConfigAccounts(t *testing.T) {
+	setConfigEnv(t, nil)
+	configFile := writeTemp(t, "satfetch.toml", `identity = "file-user"
+
+[[accounts]]
+identity = "ops1"
+password = "pass1"
+
+[[accounts]]
+identity = "ops2"
+password = "pass2"
+`)
+
+	got, err := ResolveConfig(configFile, "", "")
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	want := []Account{{"ops1", "pass1"}, {"ops2", "pass2"}}
+	if got.Identity != "file-user" || !reflect.DeepEqual(got.Accounts, want) {
+		t.Errorf("ResolveConfig = %+v, want identity file-user and accounts %+v", got, want)
+	}
+}
+
+func TestResolveConfigDefaultFile(t *testing.T) {
+	setConfigEnv(t, nil)
+	home := os.Getenv("HOME")
+	if err := os.WriteFile(filepath.Join(home, DefaultConfigFile), []byte(`identity = "home-user"`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ResolveConfig("", "", "")
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	if got.Identity != "home-user" {
+		t.Errorf("Identity = %q, want %q from ~/%s", got.Identity, "home-user", DefaultConfigFile)
+	}
+}
+
+func TestResolveConfigErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string // config file contents, if any
+		passwordFile string
+		want         string // in the error
+	}{
+		{"no equals sign", `identity "user"`, "", `:1: want key = "value"`},
+		{"unknown key", "# comment\nuser = \"user\"", "", `:2: unknown key "user"`},
+		{"unquoted value", "password = hunter2", "", ":1: password must be a quoted string"},
+		{"missing password file", "", filepath.Join(t.TempDir(), "missing"), "missing"},
+		{"empty password file", "", writeTemp(t, "empty", "\n"), "empty is empty"},
+		{"unknown section", "[account]\nidentity = \"user\"", "", ":1: unknown section [account], want [[accounts]]"},
+		{"account without a password", "[[accounts]]\nidentity = \"user\"", "", ": account 1 needs an identity and a password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfigEnv(t, nil)
+			var configFile string
+			if tt.config != "" {
+				configFile = writeTemp(t, "satfetch.toml", tt.config)
+			}
+			_, err := ResolveConfig(configFile, "", tt.passwordFile)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ResolveConfig error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package satfetch
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Conjunction is a close approach of two objects, from a conjunction data
+// message in Space Track's cdm_public class. The objects' names are as the
+// message gives them.
+type Conjunction struct {
+	CDMID         string    `json:"cdmId"`
+	TCA           time.Time `json:"tca"`                     // time of closest approach
+	MissDistanceM *float64  `json:"missDistanceM,omitempty"` // if given
+	Probability   *float64  `json:"probability,omitempty"`   // of collision, if given
+	Sat1ID        string    `json:"sat1Id"`
+	Sat1Name      string    `json:"sat1Name"`
+	Sat2ID        string    `json:"sat2Id"`
+	Sat2Name      string    `json:"sat2Name"`
+}
+
+// cdmTimeLayout is how Space Track writes times in conjunction data
+// messages.
+const cdmTimeLayout = "2006-01-02T15:04:05.999999"
+
+// FetchConjunctions queries Space Track for the conjunctions with times of
+// closest approach between start and end, soonest first.
+func (c *Client) FetchConjunctions(start time.Time, end time.Time) ([]Conjunction, error) {
+	var records []struct {
+		CDMID    string `json:"CDM_ID"`
+		TCA      string `json:"TCA"`
+		MinRange string `json:"MIN_RNG"` // metres
+		PC       string `json:"PC"`
+		Sat1ID   string `json:"SAT_1_ID"`
+		Sat1Name string `json:"SAT_1_NAME"`
+		Sat2ID   string `json:"SAT_2_ID"`
+		Sat2Name string `json:"SAT_2_NAME"`
+	}
+	err := c.queryRecords("cdm_public", &records,
+		"TCA", spaceTrackTime(start)+"--"+spaceTrackTime(end),
+		"orderby", "TCA asc")
+	if err != nil {
+		return nil, err
+	}
+
+	conjunctions := make([]Conjunction, len(records))
+	for i, r := range records {
+		c := Conjunction{
+			CDMID:    r.CDMID,
+			Sat1ID:   r.Sat1ID,
+			Sat1Name: r.Sat1Name,
+			Sat2ID:   r.Sat2ID,
+			Sat2Name: r.Sat2Name,
+		}
+		if c.TCA, err = time.Parse(cdmTimeLayout, r.TCA); err != nil {
+			return nil, fmt.Errorf("CDM %s: TCA: %v", r.CDMID, err)
+		}
+		if c.MissDistanceM, err = parseOptionalFloat(r.MinRange); err != nil {
+			return nil, fmt.Errorf("CDM %s: miss distance: %v", r.CDMID, err)
+		}
+		if c.Probability, err = parseOptionalFloat(r.PC); err != nil {
+			return nil, fmt.Errorf("CDM %s: probability: %v", r.CDMID, err)
+		}
+		conjunctions[i] = c
+	}
+
+	sort.SliceStable(conjunctions, func(i, j int) bool {
+		return conjunctions[i].TCA.Before(conjunctions[j].TCA)
+	})
+	return conjunctions, nil
+}
+
+// parseOptionalFloat parses s as a number, or returns nil if it's empty, as
+// values Space Track doesn't know are.
+func parseOptionalFloat(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
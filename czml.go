@@ -0,0 +1,152 @@
+package satfetch
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// czmlPacket is one packet of a CZML document, the JSON CesiumJS loads
+// scenes from, with the properties satfetch uses. The first packet of a
+// document describes the document itself.
+type czmlPacket struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name,omitempty"`
+	Version      string        `json:"version,omitempty"`
+	Clock        *czmlClock    `json:"clock,omitempty"`
+	Availability string        `json:"availability,omitempty"`
+	Label        *czmlLabel    `json:"label,omitempty"`
+	Point        *czmlPoint    `json:"point,omitempty"`
+	Path         *czmlPath     `json:"path,omitempty"`
+	Position     *czmlPosition `json:"position,omitempty"`
+}
+
+type czmlClock struct {
+	Interval    string  `json:"interval"`
+	CurrentTime string  `json:"currentTime"`
+	Multiplier  float64 `json:"multiplier"`
+	Range       string  `json:"range"`
+	Step        string  `json:"step"`
+}
+
+type czmlLabel struct {
+	Text        string  `json:"text"`
+	Font        string  `json:"font"`
+	PixelOffset czmlXY  `json:"pixelOffset"`
+	FillColor   czmlRGB `json:"fillColor"`
+}
+
+type czmlPoint struct {
+	PixelSize int     `json:"pixelSize"`
+	Color     czmlRGB `json:"color"`
+}
+
+type czmlPath struct {
+	Width      int     `json:"width"`
+	LeadTime   float64 `json:"leadTime"`
+	TrailTime  float64 `json:"trailTime"`
+	Resolution float64 `json:"resolution"`
+	Material   struct {
+		SolidColor struct {
+			Color czmlRGB `json:"color"`
+		} `json:"solidColor"`
+	} `json:"material"`
+}
+
+type czmlXY struct {
+	Cartesian2 [2]float64 `json:"cartesian2"`
+}
+
+type czmlRGB struct {
+	RGBA [4]int `json:"rgba"`
+}
+
+// czmlPosition holds position samples as [seconds since Epoch, x, y, z, ...]
+// in metres in the Earth-fixed frame.
+type czmlPosition struct {
+	Epoch                  string    `json:"epoch"`
+	ReferenceFrame         string    `json:"referenceFrame"`
+	InterpolationAlgorithm string    `json:"interpolationAlgorithm"`
+	InterpolationDegree    int       `json:"interpolationDegree"`
+	Cartesian              []float64 `json:"cartesian"`
+}
+
+// czmlTimeLayout is how CZML writes times, ISO 8601 in UTC.
+const czmlTimeLayout = "2006-01-02T15:04:05Z"
+
+// WriteCZML writes a CZML document to w animating the satellites of tles,
+// each propagated with SGP4 every step from start to stop and labeled with
+// its name in names, or its NORAD ID. Satellites that can't be propagated
+// at any of the times, such as ones that have decayed, are left out.
+func WriteCZML(w io.Writer, tles []TLE, names map[uint64]string, start time.Time, stop time.Time, step time.Duration) error {
+	start, stop = start.UTC(), stop.UTC()
+	interval := start.Format(czmlTimeLayout) + "/" + stop.Format(czmlTimeLayout)
+	packets := []czmlPacket{{
+		ID:      "document",
+		Name:    "satfetch",
+		Version: "1.0",
+		Clock: &czmlClock{
+			Interval:    interval,
+			CurrentTime: start.Format(czmlTimeLayout),
+			Multiplier:  60,
+			Range:       "LOOP_STOP",
+			Step:        "SYSTEM_CLOCK_MULTIPLIER",
+		},
+	}}
+
+	for _, tle := range tles {
+		var samples []float64
+		for t := start; !t.After(stop); t = t.Add(step) {
+			pos, _, err := tle.PropagateTEME(t)
+			if err != nil {
+				slog.Debug("Leaving out unpropagatable time", "norad", tle.NORADID, "time", t, "err", err)
+				continue
+			}
+			r := TEMEToECEF(pos, t)
+			samples = append(samples, t.Sub(start).Seconds(), r[0]*1000, r[1]*1000, r[2]*1000)
+		}
+		if len(samples) == 0 {
+			slog.Warn("Leaving out satellite that can't be propagated", "norad", tle.NORADID)
+			continue
+		}
+
+		name := names[tle.NORADID]
+		if name == "" {
+			name = strconv.FormatUint(tle.NORADID, 10)
+		}
+		period := tle.PeriodMinutes() * 60
+		path := &czmlPath{
+			Width:      1,
+			LeadTime:   period / 2,
+			TrailTime:  period / 2,
+			Resolution: step.Seconds(),
+		}
+		path.Material.SolidColor.Color = czmlRGB{[4]int{255, 200, 0, 128}}
+		packets = append(packets, czmlPacket{
+			ID:           strconv.FormatUint(tle.NORADID, 10),
+			Name:         name,
+			Availability: interval,
+			Label: &czmlLabel{
+				Text:        name,
+				Font:        "11pt sans-serif",
+				PixelOffset: czmlXY{[2]float64{12, 0}},
+				FillColor:   czmlRGB{[4]int{255, 255, 255, 255}},
+			},
+			Point: &czmlPoint{PixelSize: 6, Color: czmlRGB{[4]int{255, 200, 0, 255}}},
+			Path:  path,
+			Position: &czmlPosition{
+				Epoch:                  start.Format(czmlTimeLayout),
+				ReferenceFrame:         "FIXED",
+				InterpolationAlgorithm: "LAGRANGE",
+				InterpolationDegree:    5,
+				Cartesian:              samples,
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(packets)
+}
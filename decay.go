@@ -0,0 +1,48 @@
+package satfetch
+
+import "time"
+
+// Decay is a predicted or actual reentry from Space Track's decay class.
+// FetchDecays leaves ObjectType empty, for the caller to fill in from a
+// SATCAT, which also knows more objects' countries.
+type Decay struct {
+	NORADID     string `json:"noradid"`
+	ObjectName  string `json:"objectName"`
+	IntlDes     string `json:"intldes"`
+	DecayEpoch  string `json:"decayEpoch"`
+	MessageType string `json:"msgType"` // Prediction or Historical
+	Country     string `json:"country"`
+	ObjectType  string `json:"objectType"`
+}
+
+// FetchDecays queries Space Track for the decays with epochs between start
+// and end, soonest first.
+func (c *Client) FetchDecays(start time.Time, end time.Time) ([]Decay, error) {
+	var records []struct {
+		NORADID     string `json:"NORAD_CAT_ID"`
+		ObjectName  string `json:"OBJECT_NAME"`
+		IntlDes     string `json:"INTLDES"`
+		DecayEpoch  string `json:"DECAY_EPOCH"`
+		MessageType string `json:"MSG_TYPE"`
+		Country     string `json:"COUNTRY"`
+	}
+	err := c.queryRecords("decay", &records,
+		"DECAY_EPOCH", spaceTrackTime(start)+"--"+spaceTrackTime(end),
+		"orderby", "DECAY_EPOCH asc")
+	if err != nil {
+		return nil, err
+	}
+
+	decays := make([]Decay, len(records))
+	for i, r := range records {
+		decays[i] = Decay{
+			NORADID:     r.NORADID,
+			ObjectName:  r.ObjectName,
+			IntlDes:     r.IntlDes,
+			DecayEpoch:  r.DecayEpoch,
+			MessageType: r.MessageType,
+			Country:     r.Country,
+		}
+	}
+	return decays, nil
+}
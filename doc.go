@@ -0,0 +1,32 @@
+// Package satfetch fetches the satellite catalog and two-line element sets
+// from Space Track, parses them, and propagates element sets with SGP4. The
+// satfetch command, in cmd/satfetch, is built on it.
+//
+// To find where a satellite is now from its newest element set:
+//
+//	client := satfetch.NewClient(satfetch.DefaultLoginURL, satfetch.DefaultAPIRoot, identity, password)
+//	if err := client.Login(); err != nil {
+//		log.Fatal(err)
+//	}
+//	data, err := client.FetchTLE("25544")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	tles, err := satfetch.ParseTLEText(data)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	lat, lon, altKm, err := satfetch.SubPoint(tles[0], time.Now())
+//
+// ResolveConfig and NewClientFromConfig find the credentials the way the
+// command does instead, from ~/.satfetch.toml and the environment.
+//
+// Element sets already in two-line form, such as a file from CelesTrak,
+// parse with ParseTLE a pair of lines at a time, or with IterateTLEs from a
+// reader. PropagateTEME gives a satellite's position and velocity,
+// LookAngles where to point to see it from the ground, NextPasses when it
+// can be seen, and GroundTrack the path below it. FetchDecays, FetchBoxscore
+// and FetchConjunctions read Space Track's reentry, catalog summary and
+// conjunction classes. The examples show parsing, filtering a SATCAT and
+// propagating without a Space Track account.
+package satfetch
@@ -0,0 +1,75 @@
+package satfetch_test
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deorbit/satfetch"
+)
+
+func ExampleParseTLE() {
+	tle, err := satfetch.ParseTLE(
+		"1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927",
+		"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(tle.NORADID, tle.IntlDesignator, tle.EpochTime().Format(time.RFC3339))
+	fmt.Printf("%.1f min, %.0f x %.0f km\n", tle.PeriodMinutes(), tle.PerigeeKm(), tle.ApogeeKm())
+	// Output:
+	// 25544 98067A 2008-09-20T12:25:40Z
+	// 91.6 min, 348 x 357 km
+}
+
+func ExampleFilterSatcat() {
+	rows, err := satfetch.ParseSATCATCSV("testdata/satcat.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	onOrbit := satfetch.FilterSatcat(rows, satfetch.SatcatFilter{
+		ObjectType:  []string{"PAYLOAD"},
+		OnOrbitOnly: true,
+		MaxPerigee:  1000,
+	})
+	for _, row := range onOrbit {
+		fmt.Println(row.NORADID, row.SatName)
+	}
+	// Output:
+	// 5 VANGUARD 1
+	// 25544 ISS (ZARYA)
+}
+
+func ExampleTLE_PropagateTEME() {
+	tle, err := satfetch.ParseTLE(
+		"1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+		"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667")
+	if err != nil {
+		log.Fatal(err)
+	}
+	pos, vel, err := tle.PropagateTEME(tle.EpochTime().Add(6 * time.Hour))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%.2f %.2f %.2f km\n", pos[0], pos[1], pos[2])
+	fmt.Printf("%.4f %.4f %.4f km/s\n", vel[0], vel[1], vel[2])
+	// Output:
+	// -7154.03 -3783.18 -3536.19 km
+	// 4.7419 -4.1518 -2.0939 km/s
+}
+
+func ExampleSubPoint() {
+	tle, err := satfetch.ParseTLE(
+		"1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927",
+		"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537")
+	if err != nil {
+		log.Fatal(err)
+	}
+	lat, lon, altKm, err := satfetch.SubPoint(tle, tle.EpochTime())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%.2f° %.2f° %.0f km\n", lat, lon, altKm)
+	// Output:
+	// 51.46° 160.14° 355 km
+}
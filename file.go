@@ -0,0 +1,101 @@
+package satfetch
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GzipExt is the suffix of gzip-compressed files. OpenFile, ReadFile and
+// WriteFile compress and decompress files with it transparently.
+const GzipExt = ".gz"
+
+// gzipFile closes the file under a gzip.Reader along with it.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// OpenFile opens filename for reading, decompressing it if its name ends in
+// GzipExt.
+func OpenFile(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil || !strings.HasSuffix(filename, GzipExt) {
+		return file, err
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return gzipFile{zr, file}, nil
+}
+
+// ReadFile reads the whole of filename, decompressing it if its name ends in
+// GzipExt.
+func ReadFile(filename string) ([]byte, error) {
+	r, err := OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return data, nil
+}
+
+// WriteFile writes data to filename like os.WriteFile, compressing it if the
+// name ends in GzipExt. The data goes to a temporary file in the same
+// directory that is renamed into place once complete, so filename never
+// holds part of it.
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	// Once renamed, there's nothing left here to remove.
+	defer os.Remove(f.Name())
+
+	if strings.HasSuffix(filename, GzipExt) {
+		err = writeGzip(f, data)
+	} else {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// writeGzip writes data to w as a complete gzip stream. Nothing is written
+// for empty data but the gzip header and trailer, so the result still reads
+// back as empty.
+func writeGzip(w io.Writer, data []byte) error {
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	// Close flushes what's buffered and writes the trailer.
+	return zw.Close()
+}
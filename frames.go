@@ -0,0 +1,114 @@
+package satfetch
+
+import (
+	"math"
+	"time"
+)
+
+// WGS-84 ellipsoid, for observer coordinates.
+const (
+	wgs84RadiusKm   = 6378.137
+	wgs84Flattening = 1 / 298.257223563
+)
+
+// LookAngles returns where to point from an observer at the given geodetic
+// latitude and longitude in degrees and altitude in metres to see the
+// satellite at t: azimuth in degrees clockwise from true north, elevation in
+// degrees above the horizon, negative if below it, and slant range in km.
+func LookAngles(tle TLE, observerLat float64, observerLon float64, observerAltM float64, t time.Time) (az float64, el float64, rangeKm float64, err error) {
+	pos, _, err := tle.PropagateTEME(t)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	sat := TEMEToECEF(pos, t)
+	obs := geodeticToECEF(observerLat, observerLon, observerAltM/1000)
+
+	var rho [3]float64
+	for i := range rho {
+		rho[i] = sat[i] - obs[i]
+	}
+	rangeKm = math.Sqrt(rho[0]*rho[0] + rho[1]*rho[1] + rho[2]*rho[2])
+
+	// Rotate the range vector into east, north and up at the observer.
+	lat := observerLat * math.Pi / 180
+	lon := observerLon * math.Pi / 180
+	sinLat, cosLat := math.Sincos(lat)
+	sinLon, cosLon := math.Sincos(lon)
+	east := -sinLon*rho[0] + cosLon*rho[1]
+	north := -sinLat*cosLon*rho[0] - sinLat*sinLon*rho[1] + cosLat*rho[2]
+	up := cosLat*cosLon*rho[0] + cosLat*sinLon*rho[1] + sinLat*rho[2]
+
+	az = math.Atan2(east, north) * 180 / math.Pi
+	if az < 0 {
+		az += 360
+	}
+	el = math.Asin(up/rangeKm) * 180 / math.Pi
+	return az, el, rangeKm, nil
+}
+
+// TEMEToECEF rotates a TEME vector into the Earth-fixed frame at t by
+// Greenwich mean sidereal time, ignoring polar motion.
+func TEMEToECEF(r [3]float64, t time.Time) [3]float64 {
+	sinG, cosG := math.Sincos(gstime(julianDate(t)))
+	return [3]float64{
+		cosG*r[0] + sinG*r[1],
+		-sinG*r[0] + cosG*r[1],
+		r[2],
+	}
+}
+
+// geodeticToECEF returns the Earth-fixed position in km of a point at the
+// given WGS-84 latitude and longitude in degrees and height in km.
+func geodeticToECEF(lat float64, lon float64, heightKm float64) [3]float64 {
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	n := wgs84RadiusKm / math.Sqrt(1-e2*sinLat*sinLat)
+	return [3]float64{
+		(n + heightKm) * cosLat * cosLon,
+		(n + heightKm) * cosLat * sinLon,
+		(n*(1-e2) + heightKm) * sinLat,
+	}
+}
+
+// SubPoint returns the point on the ground directly below the satellite at
+// t: its WGS-84 geodetic latitude and longitude in degrees, longitude in
+// [-180, 180], and its height above the ellipsoid in km.
+func SubPoint(tle TLE, t time.Time) (latDeg float64, lonDeg float64, altKm float64, err error) {
+	pos, _, err := tle.PropagateTEME(t)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	latDeg, lonDeg, altKm = ecefToGeodetic(TEMEToECEF(pos, t))
+	return latDeg, lonDeg, altKm, nil
+}
+
+// ecefToGeodetic is the inverse of geodeticToECEF, iterating on the latitude
+// until it settles to well under a millimetre.
+func ecefToGeodetic(r [3]float64) (lat float64, lon float64, heightKm float64) {
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	p := math.Hypot(r[0], r[1])
+	lon = math.Atan2(r[1], r[0])
+
+	lat = math.Atan2(r[2], p*(1-e2))
+	for i := 0; i < 10; i++ {
+		sinLat := math.Sin(lat)
+		n := wgs84RadiusKm / math.Sqrt(1-e2*sinLat*sinLat)
+		next := math.Atan2(r[2]+e2*n*sinLat, p)
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	sinLat, cosLat := math.Sincos(lat)
+	n := wgs84RadiusKm / math.Sqrt(1-e2*sinLat*sinLat)
+	if cosLat > 1e-10 {
+		heightKm = p/cosLat - n
+	} else {
+		// Over a pole, where p/cosLat is 0/0.
+		heightKm = math.Abs(r[2])/math.Abs(sinLat) - n*(1-e2)
+	}
+	return lat * 180 / math.Pi, lon * 180 / math.Pi, heightKm
+}
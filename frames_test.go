@@ -0,0 +1,41 @@
+package satfetch
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// greenwich is the Royal Observatory, Greenwich: latitude and longitude in
+// degrees and altitude in metres.
+var greenwich = [3]float64{51.4779, -0.0015, 46}
+
+func TestLookAngles(t *testing.T) {
+	tle, err := ParseTLE(issLine1, issLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+
+	// The references reduce the ISS's SGP4 position to Greenwich
+	// independently: the observer is rotated into TEME by local mean
+	// sidereal time (IAU 1982 GMST) instead of the satellite out of it.
+	tests := []struct {
+		at          time.Time
+		az, el, rng float64 // degrees, degrees, km
+	}{
+		{time.Date(2008, 9, 20, 19, 52, 0, 0, time.UTC), 222.9855, 1.3516, 2012.0515},  // rising
+		{time.Date(2008, 9, 20, 19, 56, 0, 0, time.UTC), 165.9028, 27.0478, 715.5873},  // near culmination
+		{time.Date(2008, 9, 20, 21, 31, 30, 0, time.UTC), 168.5399, 83.4958, 357.9398}, // nearly overhead
+	}
+	for _, tt := range tests {
+		az, el, rng, err := LookAngles(tle, greenwich[0], greenwich[1], greenwich[2], tt.at)
+		if err != nil {
+			t.Errorf("%v: %v", tt.at, err)
+			continue
+		}
+		if math.Abs(az-tt.az) > 0.001 || math.Abs(el-tt.el) > 0.001 || math.Abs(rng-tt.rng) > 0.001 {
+			t.Errorf("LookAngles at %v = %.4f°, %.4f°, %.4f km, want %.4f°, %.4f°, %.4f km",
+				tt.at, az, el, rng, tt.az, tt.el, tt.rng)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/deorbit/satfetch
+
+go 1.21
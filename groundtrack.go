@@ -0,0 +1,232 @@
+package satfetch
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// GroundPoint is a point of a satellite's ground track.
+type GroundPoint struct {
+	Time  time.Time
+	Lat   float64 // WGS-84 geodetic latitude, degrees
+	Lon   float64 // degrees, in [-180, 180]
+	AltKm float64 // height above the ellipsoid
+
+	// Crossing is what the satellite crosses at Time, if the point was
+	// added for a crossing.
+	Crossing Crossing
+}
+
+// Crossing is a moment GroundTrack can add to a track.
+type Crossing int
+
+const (
+	NoCrossing     Crossing = iota
+	AscendingNode           // crossing the equator northward
+	DescendingNode          // crossing the equator southward
+	Rise                    // rising through an observer's minimum elevation
+	Set                     // setting through it
+)
+
+func (c Crossing) String() string {
+	switch c {
+	case AscendingNode:
+		return "ascending node"
+	case DescendingNode:
+		return "descending node"
+	case Rise:
+		return "rise"
+	case Set:
+		return "set"
+	}
+	return ""
+}
+
+// GroundTrackOption configures GroundTrack.
+type GroundTrackOption func(*groundTrack)
+
+// groundTrack is what GroundTrackOptions set.
+type groundTrack struct {
+	equator bool
+
+	horizon                  bool
+	observerLat, observerLon float64
+	observerAltM             float64
+	minElevation             float64
+}
+
+// WithEquatorCrossings adds the points where the track crosses the equator,
+// found to the second by bisection.
+func WithEquatorCrossings() GroundTrackOption {
+	return func(g *groundTrack) {
+		g.equator = true
+	}
+}
+
+// WithHorizonCrossings adds the points where the satellite rises above and
+// sets below minElevation degrees, as seen from the observer at lat, lon in
+// degrees and altM in metres, found to the second by bisection the way
+// NextPasses finds AOS and LOS.
+func WithHorizonCrossings(lat float64, lon float64, altM float64, minElevation float64) GroundTrackOption {
+	return func(g *groundTrack) {
+		g.horizon = true
+		g.observerLat, g.observerLon, g.observerAltM = lat, lon, altM
+		g.minElevation = minElevation
+	}
+}
+
+const (
+	// groundTrackToleranceKm is how far GroundTrack lets the true track
+	// stray from a straight line between its points, in latitude and
+	// longitude, before adding another between them.
+	groundTrackToleranceKm = 1
+	// groundTrackMinStep is how close together GroundTrack adds points
+	// at most.
+	groundTrackMinStep = time.Second
+)
+
+// GroundTrack returns the points below the satellite every step from start
+// to stop, and at stop, with points added between them wherever a straight
+// line between two would stray from the track, so the track can be drawn
+// smoothly however coarse step is. Times the satellite can't be propagated
+// to, such as after it decays, are left out. Options add the moments of
+// crossings as points of their own. The track isn't split at the
+// antimeridian: longitudes stay in [-180, 180], jumping by nearly 360°
+// between the points either side of it.
+func GroundTrack(tle TLE, start time.Time, stop time.Time, step time.Duration, opts ...GroundTrackOption) []GroundPoint {
+	var g groundTrack
+	for _, opt := range opts {
+		opt(&g)
+	}
+	if step <= 0 || stop.Before(start) {
+		return nil
+	}
+
+	point := func(t time.Time) (GroundPoint, bool) {
+		lat, lon, altKm, err := SubPoint(tle, t)
+		return GroundPoint{Time: t, Lat: lat, Lon: lon, AltKm: altKm}, err == nil
+	}
+
+	var track []GroundPoint
+	var refine func(a GroundPoint, b GroundPoint)
+	refine = func(a GroundPoint, b GroundPoint) {
+		span := b.Time.Sub(a.Time)
+		if span < 2*groundTrackMinStep {
+			return
+		}
+		mid, ok := point(a.Time.Add(span / 2))
+		if !ok {
+			return
+		}
+		lat := (a.Lat + b.Lat) / 2
+		lon := a.Lon + lonDelta(a.Lon, b.Lon)/2
+		if groundTrackStrayKm(mid, lat, lon) <= groundTrackToleranceKm {
+			return
+		}
+		refine(a, mid)
+		track = append(track, mid)
+		refine(mid, b)
+	}
+
+	for t := start; ; t = t.Add(step) {
+		if t.After(stop) {
+			t = stop
+		}
+		if p, ok := point(t); ok {
+			if len(track) > 0 {
+				refine(track[len(track)-1], p)
+			}
+			track = append(track, p)
+		}
+		if !t.Before(stop) {
+			break
+		}
+	}
+
+	if !g.equator && !g.horizon {
+		return track
+	}
+
+	// elevation is less the minimum elevation, positive while the
+	// observer can see the satellite.
+	elevation := func(t time.Time) float64 {
+		_, el, _, _ := LookAngles(tle, g.observerLat, g.observerLon, g.observerAltM, t)
+		return el - g.minElevation
+	}
+	latitude := func(t time.Time) float64 {
+		p, _ := point(t)
+		return p.Lat
+	}
+	crossingAt := func(t time.Time, c Crossing) GroundPoint {
+		p, _ := point(t)
+		p.Crossing = c
+		return p
+	}
+
+	withCrossings := make([]GroundPoint, 0, len(track))
+	var prevElevation float64
+	for i, p := range track {
+		var crossings []GroundPoint
+		if i > 0 {
+			prev := track[i-1]
+			if g.equator && (prev.Lat > 0) != (p.Lat > 0) {
+				c := AscendingNode
+				if prev.Lat > 0 {
+					c = DescendingNode
+				}
+				crossings = append(crossings, crossingAt(refineCrossing(latitude, prev.Time, p.Time), c))
+			}
+		}
+		if g.horizon && i == 0 {
+			prevElevation = elevation(p.Time)
+		} else if g.horizon {
+			// Points can be far apart where the track runs straight, so
+			// look at least as often as NextPasses does, not to miss
+			// short passes.
+			for a := track[i-1].Time; a.Before(p.Time); {
+				b := a.Add(passStep)
+				if b.After(p.Time) {
+					b = p.Time
+				}
+				el := elevation(b)
+				if (prevElevation > 0) != (el > 0) {
+					c := Rise
+					if prevElevation > 0 {
+						c = Set
+					}
+					crossings = append(crossings, crossingAt(refineCrossing(elevation, a, b), c))
+				}
+				prevElevation = el
+				a = b
+			}
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].Time.Before(crossings[j].Time) })
+		withCrossings = append(withCrossings, crossings...)
+		withCrossings = append(withCrossings, p)
+	}
+	return withCrossings
+}
+
+// lonDelta returns how many degrees east lon2 is of lon1, the short way
+// round, in [-180, 180].
+func lonDelta(lon1 float64, lon2 float64) float64 {
+	d := math.Mod(lon2-lon1, 360)
+	switch {
+	case d > 180:
+		d -= 360
+	case d < -180:
+		d += 360
+	}
+	return d
+}
+
+// groundTrackStrayKm returns roughly how far p is from lat, lon in degrees,
+// treating the ground as flat there, which is near enough for the short
+// distances GroundTrack compares with groundTrackToleranceKm.
+func groundTrackStrayKm(p GroundPoint, lat float64, lon float64) float64 {
+	const kmPerDegree = math.Pi * wgs84RadiusKm / 180
+	dLat := p.Lat - lat
+	dLon := lonDelta(lon, p.Lon) * math.Cos(p.Lat*math.Pi/180)
+	return math.Hypot(dLat, dLon) * kmPerDegree
+}
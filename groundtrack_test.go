@@ -0,0 +1,57 @@
+package satfetch
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGroundTrackAntimeridian(t *testing.T) {
+	tle, err := ParseTLE(issLine1, issLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+	start := tle.EpochTime()
+	track := GroundTrack(tle, start, start.Add(6*time.Hour), 5*time.Minute)
+
+	// The ISS crosses the antimeridian eastward about once an orbit. The
+	// track isn't split there: longitudes wrap from near 180 to near -180
+	// between neighbouring points.
+	wraps := 0
+	for i := 1; i < len(track); i++ {
+		a, b := track[i-1], track[i]
+		for _, p := range []GroundPoint{a, b} {
+			if p.Lon < -180 || p.Lon > 180 {
+				t.Fatalf("point at %v has longitude %g, want it in [-180, 180]", p.Time, p.Lon)
+			}
+		}
+		// Eastward the short way round, and never by much.
+		if d := lonDelta(a.Lon, b.Lon); d <= 0 || d > 30 {
+			t.Errorf("%v to %v: longitude %g to %g, %g° east, want a short way east", a.Time, b.Time, a.Lon, b.Lon, d)
+		}
+		if b.Lon >= a.Lon {
+			continue
+		}
+		wraps++
+		// Refinement measures across the antimeridian the short way,
+		// so it needn't add points there any closer than elsewhere.
+		if gap := b.Time.Sub(a.Time); gap < 10*time.Second {
+			t.Errorf("points across the antimeridian at %v are %v apart", a.Time, gap)
+		}
+		lat, lon, _, err := SubPoint(tle, a.Time.Add(b.Time.Sub(a.Time)/2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mid := GroundPoint{Lat: lat, Lon: lon}
+		if d := groundTrackStrayKm(mid, (a.Lat+b.Lat)/2, a.Lon+lonDelta(a.Lon, b.Lon)/2); d > groundTrackToleranceKm {
+			t.Errorf("the track strays %.1f km from the line across the antimeridian at %v", d, a.Time)
+		}
+		if math.Abs(a.Lon) < 150 || math.Abs(b.Lon) < 150 {
+			t.Errorf("longitude wraps from %g to %g, away from the antimeridian", a.Lon, b.Lon)
+		}
+	}
+	// Six hours is nearly four orbits.
+	if wraps < 3 || wraps > 4 {
+		t.Errorf("track wraps %d times in 6 hours, want 3 or 4", wraps)
+	}
+}
@@ -0,0 +1,198 @@
+package satfetch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ommEpochLayout is how OMMs write epochs, in UTC.
+const ommEpochLayout = "2006-01-02T15:04:05.999999"
+
+// omm is a CCSDS Orbit Mean-Elements Message in XML, as Space Track's gp
+// classes give with format/xml, with the parts a TLE holds.
+type omm struct {
+	XMLName xml.Name `xml:"omm"`
+	ID      string   `xml:"id,attr"`
+	Version string   `xml:"version,attr"`
+	Header  struct {
+		CreationDate string `xml:"CREATION_DATE"`
+		Originator   string `xml:"ORIGINATOR"`
+	} `xml:"header"`
+	Metadata struct {
+		ObjectName        string `xml:"OBJECT_NAME"`
+		ObjectID          string `xml:"OBJECT_ID"`
+		CenterName        string `xml:"CENTER_NAME"`
+		RefFrame          string `xml:"REF_FRAME"`
+		TimeSystem        string `xml:"TIME_SYSTEM"`
+		MeanElementTheory string `xml:"MEAN_ELEMENT_THEORY"`
+	} `xml:"body>segment>metadata"`
+	MeanElements struct {
+		Epoch        string  `xml:"EPOCH"`
+		MeanMotion   float64 `xml:"MEAN_MOTION"`
+		Eccentricity float64 `xml:"ECCENTRICITY"`
+		Inclination  float64 `xml:"INCLINATION"`
+		RAAN         float64 `xml:"RA_OF_ASC_NODE"`
+		ArgOfPerigee float64 `xml:"ARG_OF_PERICENTER"`
+		MeanAnomaly  float64 `xml:"MEAN_ANOMALY"`
+	} `xml:"body>segment>data>meanElements"`
+	TLEParameters struct {
+		EphemerisType  int     `xml:"EPHEMERIS_TYPE"`
+		Classification string  `xml:"CLASSIFICATION_TYPE"`
+		NORADID        uint64  `xml:"NORAD_CAT_ID"`
+		ElementSetNo   int     `xml:"ELEMENT_SET_NO"`
+		RevAtEpoch     uint32  `xml:"REV_AT_EPOCH"`
+		BSTAR          float64 `xml:"BSTAR"`
+		MeanMotionDot  float64 `xml:"MEAN_MOTION_DOT"`
+		MeanMotionDDot float64 `xml:"MEAN_MOTION_DDOT"`
+	} `xml:"body>segment>data>tleParameters"`
+}
+
+// ParseOMM decodes the OMMs in r, either a lone <omm> or several in an
+// <ndm> as Space Track sends them, into TLEs named by their OBJECT_NAMEs.
+// OMMs carry no checksums, so the TLEs get those of their two-line form.
+func ParseOMM(r io.Reader) ([]TLE, error) {
+	var tles []TLE
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return tles, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding OMM: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "omm" {
+			continue
+		}
+
+		var m omm
+		if err := dec.DecodeElement(&m, &start); err != nil {
+			return nil, fmt.Errorf("decoding OMM: %v", err)
+		}
+		tle, err := m.tle()
+		if err != nil {
+			return nil, fmt.Errorf("OMM %d: %v", len(tles)+1, err)
+		}
+		tles = append(tles, tle)
+	}
+}
+
+// tle converts the OMM to a TLE.
+func (m omm) tle() (TLE, error) {
+	epoch, err := time.Parse(ommEpochLayout, m.MeanElements.Epoch)
+	if err != nil {
+		return TLE{}, fmt.Errorf("bad epoch: %v", err)
+	}
+	midnight := time.Date(epoch.Year(), epoch.Month(), epoch.Day(), 0, 0, 0, 0, time.UTC)
+
+	// OBJECT_ID is the designator in full, 1998-067A, where TLEs have 98067A.
+	intlDes := m.Metadata.ObjectID
+	if len(intlDes) >= 8 && intlDes[4] == '-' {
+		intlDes = intlDes[2:4] + intlDes[5:]
+	}
+
+	p := m.TLEParameters
+	tle := TLE{
+		NORADID:        p.NORADID,
+		Classification: p.Classification,
+		IntlDesignator: intlDes,
+		Epoch: float64(epoch.Year()%100*1000+epoch.YearDay()) +
+			epoch.Sub(midnight).Seconds()/86400,
+		MnMot1stDeriv:    p.MeanMotionDot,
+		MnMot2ndDeriv:    p.MeanMotionDDot,
+		BSTAR:            p.BSTAR,
+		EphemerisType:    p.EphemerisType,
+		ElementSetNumber: p.ElementSetNo,
+		Inclination:      float32(m.MeanElements.Inclination),
+		RAAN:             float32(m.MeanElements.RAAN),
+		Eccentricity:     float32(m.MeanElements.Eccentricity),
+		ArgOfPerigee:     float32(m.MeanElements.ArgOfPerigee),
+		MeanAnomaly:      float32(m.MeanElements.MeanAnomaly),
+		MeanMotion:       m.MeanElements.MeanMotion,
+		RevNumber:        p.RevAtEpoch,
+		Name:             strings.TrimSpace(m.Metadata.ObjectName),
+	}
+	if tle.NORADID == 0 || tle.NORADID > 99999 {
+		return TLE{}, fmt.Errorf("NORAD_CAT_ID %d is missing or too big for a TLE", p.NORADID)
+	}
+
+	lines := strings.Split(tle.TwoLine(), "\n")
+	tle.Line1Checksum, tle.Line2Checksum = TLEChecksum(lines[0]), TLEChecksum(lines[1])
+	return tle, nil
+}
+
+// newOMM returns the OMM for tle, as Space Track would write it.
+func newOMM(tle TLE) omm {
+	var m omm
+	m.ID = "CCSDS_OMM_VERS"
+	m.Version = "2.0"
+	m.Header.CreationDate = time.Now().UTC().Format(ommEpochLayout)
+	m.Header.Originator = "18 SPCS"
+
+	m.Metadata.ObjectID = tle.IntlDesignator
+	if des := strings.TrimSpace(tle.IntlDesignator); len(des) >= 5 {
+		year := "19"
+		if des[:2] < "57" {
+			year = "20"
+		}
+		m.Metadata.ObjectID = year + des[:2] + "-" + des[2:]
+	}
+	m.Metadata.CenterName = "EARTH"
+	m.Metadata.RefFrame = "TEME"
+	m.Metadata.TimeSystem = "UTC"
+	m.Metadata.MeanElementTheory = "SGP4"
+
+	me := &m.MeanElements
+	me.Epoch = tle.EpochTime().Format(ommEpochLayout)
+	me.MeanMotion = tle.MeanMotion
+	me.Eccentricity = roundTo(float64(tle.Eccentricity), 7)
+	me.Inclination = roundTo(float64(tle.Inclination), 4)
+	me.RAAN = roundTo(float64(tle.RAAN), 4)
+	me.ArgOfPerigee = roundTo(float64(tle.ArgOfPerigee), 4)
+	me.MeanAnomaly = roundTo(float64(tle.MeanAnomaly), 4)
+
+	p := &m.TLEParameters
+	p.EphemerisType = tle.EphemerisType
+	p.Classification = tle.Classification
+	p.NORADID = tle.NORADID
+	p.ElementSetNo = tle.ElementSetNumber
+	p.RevAtEpoch = tle.RevNumber
+	p.BSTAR = tle.BSTAR
+	p.MeanMotionDot = tle.MnMot1stDeriv
+	p.MeanMotionDDot = tle.MnMot2ndDeriv
+	return m
+}
+
+// roundTo rounds x to the given number of decimal places, to undo the
+// float32 noise in TLE angles.
+func roundTo(x float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(x*scale) / scale
+}
+
+// EncodeOMM writes tles as OMMs in an <ndm>, the way Space Track does.
+func EncodeOMM(tles []TLE) ([]byte, error) {
+	doc := struct {
+		XMLName xml.Name `xml:"ndm"`
+		OMMs    []omm    `xml:"omm"`
+	}{}
+	for _, tle := range tles {
+		doc.OMMs = append(doc.OMMs, newOMM(tle))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
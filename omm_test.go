@@ -0,0 +1,54 @@
+package satfetch
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseOMM(t *testing.T) {
+	f, err := os.Open("testdata/gp.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tles, err := ParseOMM(f)
+	if err != nil {
+		t.Fatalf("ParseOMM: %v", err)
+	}
+
+	// The OMMs hold what these element sets do, and the objects' names.
+	want := []struct {
+		name         string
+		line1, line2 string
+	}{
+		{"ISS (ZARYA)", issLine1, issLine2},
+		{"NAVSTAR 54 (USA 175)", gpsLine1, gpsLine2},
+	}
+	if len(tles) != len(want) {
+		t.Fatalf("ParseOMM returned %d element sets, want %d", len(tles), len(want))
+	}
+	for i, w := range want {
+		if tles[i].Name != w.name {
+			t.Errorf("element set %d is named %q, want %q", i, tles[i].Name, w.name)
+		}
+		if text := tles[i].Text(); text != w.line1+"\n"+w.line2+"\n" {
+			t.Errorf("element set %d =\n%s\nwant\n%s\n%s", i, text, w.line1, w.line2)
+		}
+		if err := tles[i].VerifyChecksum(); err != nil {
+			t.Errorf("element set %d: VerifyChecksum: %v", i, err)
+		}
+		if !tles[i].EpochTime().Equal(mustParseTLE(t, w.line1, w.line2).EpochTime()) {
+			t.Errorf("element set %d epoch = %s", i, tles[i].EpochTime())
+		}
+	}
+}
+
+// mustParseTLE parses an element set or fails the test.
+func mustParseTLE(t *testing.T, line1 string, line2 string) TLE {
+	t.Helper()
+	tle, err := ParseTLE(line1, line2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+	return tle
+}
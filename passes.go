@@ -0,0 +1,117 @@
+package satfetch
+
+import (
+	"time"
+)
+
+// Pass is a satellite's trip across an observer's sky.
+type Pass struct {
+	AOS        time.Time // acquisition of signal: rising through the minimum elevation
+	AOSAzimuth float64   // degrees
+	LOS        time.Time // loss of signal: setting through the minimum elevation
+	LOSAzimuth float64   // degrees
+
+	MaxElevation     float64 // degrees
+	MaxElevationTime time.Time
+}
+
+const (
+	// passStep is how far apart NextPasses samples elevations. Passes
+	// shorter than this can be missed.
+	passStep = 30 * time.Second
+	// passSearchLimit is how far past its start NextPasses gives up.
+	passSearchLimit = 30 * 24 * time.Hour
+)
+
+// NextPasses returns up to count passes of the satellite above minElevation
+// degrees, as seen from the observer at lat, lon in degrees and altM in
+// metres, starting at from. A pass already under way at from is returned
+// with from as its AOS. It stops looking 30 days after from, so satellites
+// that never rise, such as distant geostationary ones, give no passes.
+func NextPasses(tle TLE, lat float64, lon float64, altM float64, from time.Time, count int, minElevation float64) ([]Pass, error) {
+	var passes []Pass
+	if count <= 0 {
+		return passes, nil
+	}
+
+	// above is elevation less minElevation, positive while in the pass.
+	var lookErr error
+	above := func(t time.Time) float64 {
+		_, el, _, err := LookAngles(tle, lat, lon, altM, t)
+		if err != nil && lookErr == nil {
+			lookErr = err
+		}
+		return el - minElevation
+	}
+	azimuth := func(t time.Time) float64 {
+		az, _, _, _ := LookAngles(tle, lat, lon, altM, t)
+		return az
+	}
+
+	var pass *Pass
+	if above(from) > 0 {
+		pass = &Pass{AOS: from, AOSAzimuth: azimuth(from)}
+	}
+
+	prev := from
+	for t := from.Add(passStep); t.Sub(from) <= passSearchLimit; t = t.Add(passStep) {
+		up := above(t) > 0
+		if lookErr != nil {
+			return passes, lookErr
+		}
+
+		switch {
+		case pass == nil && up:
+			aos := refineCrossing(above, prev, t)
+			pass = &Pass{AOS: aos, AOSAzimuth: azimuth(aos)}
+		case pass != nil && !up:
+			pass.LOS = refineCrossing(above, prev, t)
+			pass.LOSAzimuth = azimuth(pass.LOS)
+			pass.MaxElevationTime = refineMaximum(above, pass.AOS, pass.LOS)
+			pass.MaxElevation = above(pass.MaxElevationTime) + minElevation
+			if lookErr != nil {
+				return passes, lookErr
+			}
+
+			passes = append(passes, *pass)
+			if len(passes) == count {
+				return passes, nil
+			}
+			pass = nil
+		}
+		prev = t
+	}
+	return passes, nil
+}
+
+// refineCrossing narrows down when f changes sign between a and b by
+// bisection, to the second.
+func refineCrossing(f func(time.Time) float64, a time.Time, b time.Time) time.Time {
+	aPositive := f(a) > 0
+	for b.Sub(a) > time.Second {
+		mid := a.Add(b.Sub(a) / 2)
+		if (f(mid) > 0) == aPositive {
+			a = mid
+		} else {
+			b = mid
+		}
+	}
+	return b
+}
+
+// refineMaximum finds when f peaks between a and b by golden section
+// search, to the second, assuming it has a single peak there.
+func refineMaximum(f func(time.Time) float64, a time.Time, b time.Time) time.Time {
+	const invPhi = 0.6180339887498949
+	for b.Sub(a) > time.Second {
+		span := time.Duration(float64(b.Sub(a)) * invPhi)
+		c := b.Add(-span)
+		d := a.Add(span)
+		if f(c) > f(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	return a.Add(b.Sub(a) / 2)
+}
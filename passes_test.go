@@ -0,0 +1,80 @@
+package satfetch
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNextPasses(t *testing.T) {
+	tle, err := ParseTLE(issLine1, issLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+
+	// The ISS's first passes over Greenwich after its epoch, found by
+	// sampling its elevation every second: when it rises above and sets
+	// below the horizon, and its highest elevation. NextPasses refines
+	// times to the second, so the elevation at AOS can be a few hundredths
+	// of a degree past the minimum.
+	type refPass struct {
+		aos, los, max string // UTC, 20 September 2008
+		maxElevation  float64
+	}
+	tests := []struct {
+		name         string
+		minElevation float64
+		want         []refPass
+	}{
+		{"horizon", 0, []refPass{
+			{"18:18:37", "18:25:04", "18:21:50", 5.2276},
+			{"19:51:39", "20:01:07", "19:56:22", 28.0252},
+			{"21:26:33", "21:36:27", "21:31:30", 83.4919},
+		}},
+		// The first, low, pass never reaches 10°.
+		{"10 degrees", 10, []refPass{
+			{"", "", "19:56:22", 28.0252},
+			{"", "", "21:31:30", 83.4919},
+			{"", "", "23:06:51", 84.9596},
+		}},
+	}
+
+	at := func(hms string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04:05", "2008-09-20 "+hms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	near := func(got time.Time, hms string) bool {
+		d := got.Sub(at(hms))
+		return d > -time.Second && d < time.Second
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passes, err := NextPasses(tle, greenwich[0], greenwich[1], greenwich[2], tle.EpochTime(), len(tt.want), tt.minElevation)
+			if err != nil {
+				t.Fatalf("NextPasses: %v", err)
+			}
+			if len(passes) != len(tt.want) {
+				t.Fatalf("NextPasses returned %d passes, want %d", len(passes), len(tt.want))
+			}
+			for i, want := range tt.want {
+				p := passes[i]
+				if want.aos != "" && (!near(p.AOS, want.aos) || !near(p.LOS, want.los)) {
+					t.Errorf("pass %d: AOS %v, LOS %v, want %s, %s", i, p.AOS, p.LOS, want.aos, want.los)
+				}
+				if !near(p.MaxElevationTime, want.max) || math.Abs(p.MaxElevation-want.maxElevation) > 0.05 {
+					t.Errorf("pass %d: max elevation %.4f° at %v, want %.4f° at %s", i, p.MaxElevation, p.MaxElevationTime, want.maxElevation, want.max)
+				}
+				if !p.AOS.Before(p.MaxElevationTime) || !p.MaxElevationTime.Before(p.LOS) {
+					t.Errorf("pass %d: AOS %v, max %v, LOS %v out of order", i, p.AOS, p.MaxElevationTime, p.LOS)
+				}
+				if _, el, _, _ := LookAngles(tle, greenwich[0], greenwich[1], greenwich[2], p.AOS); math.Abs(el-tt.minElevation) > 0.1 {
+					t.Errorf("pass %d: elevation at AOS is %.4f°, want %g°", i, el, tt.minElevation)
+				}
+			}
+		})
+	}
+}
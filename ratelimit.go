@@ -0,0 +1,115 @@
+package satfetch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Space Track's published query limits.
+const (
+	DefaultRequestsPerMinute = 30
+	DefaultRequestsPerHour   = 300
+)
+
+// rateLimit allows n requests in any window of length per.
+type rateLimit struct {
+	n   int
+	per time.Duration
+}
+
+// rateLimiter hands out request slots so that every one of its limits holds
+// over a sliding window. Slots are granted in order of arrival.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limits []rateLimit
+	// slots holds the times of granted requests, oldest first, pruned to
+	// the longest window.
+	slots []time.Time
+}
+
+// newRateLimiter returns a limiter enforcing the given limits. Limits with a
+// non-positive count are ignored.
+func newRateLimiter(limits ...rateLimit) *rateLimiter {
+	l := &rateLimiter{}
+	for _, limit := range limits {
+		if limit.n > 0 {
+			l.limits = append(l.limits, limit)
+		}
+	}
+	return l
+}
+
+// reserve claims the earliest slot at or after now that satisfies every
+// limit and returns its time.
+func (l *rateLimiter) reserve(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var longest time.Duration
+	for _, limit := range l.limits {
+		if limit.per > longest {
+			longest = limit.per
+		}
+	}
+	for len(l.slots) > 0 && now.Sub(l.slots[0]) >= longest {
+		l.slots = l.slots[1:]
+	}
+
+	at := now
+	if len(l.slots) > 0 && l.slots[len(l.slots)-1].After(at) {
+		at = l.slots[len(l.slots)-1]
+	}
+	for _, limit := range l.limits {
+		if len(l.slots) >= limit.n {
+			if next := l.slots[len(l.slots)-limit.n].Add(limit.per); next.After(at) {
+				at = next
+			}
+		}
+	}
+
+	l.slots = append(l.slots, at)
+	return at
+}
+
+// cancel gives back a slot returned by reserve that will not be used.
+func (l *rateLimiter) cancel(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.slots) - 1; i >= 0; i-- {
+		if l.slots[i].Equal(at) {
+			l.slots = append(l.slots[:i], l.slots[i+1:]...)
+			return
+		}
+	}
+}
+
+// Wait blocks until a request may be made. It returns an error without
+// waiting if the next slot falls after ctx's deadline, or if ctx is done
+// first.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if len(l.limits) == 0 {
+		return ctx.Err()
+	}
+
+	now := time.Now()
+	at := l.reserve(now)
+	if deadline, ok := ctx.Deadline(); ok && at.After(deadline) {
+		l.cancel(at)
+		return fmt.Errorf("rate limit: next request slot in %v is past the deadline",
+			at.Sub(now).Round(time.Second))
+	}
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(at)
+		return ctx.Err()
+	}
+}
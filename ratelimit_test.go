@@ -0,0 +1,73 @@
+package satfetch
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := newRateLimiter(rateLimit{2, time.Second}, rateLimit{3, time.Minute})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two fit in the first second, the third waits for the next, and the
+	// fourth for the minute to pass.
+	want := []time.Time{start, start, start.Add(time.Second), start.Add(time.Minute)}
+	for i, w := range want {
+		if got := l.reserve(start); !got.Equal(w) {
+			t.Errorf("slot %d at %s, want %s", i, got.Sub(start), w.Sub(start))
+		}
+	}
+
+	// Once the window has passed, a slot is free at once.
+	later := start.Add(2 * time.Minute)
+	if got := l.reserve(later); !got.Equal(later) {
+		t.Errorf("slot after the window at %s, want %s", got.Sub(start), later.Sub(start))
+	}
+}
+
+func TestRateLimiterWaitBlocks(t *testing.T) {
+	l := newRateLimiter(rateLimit{1, 100 * time.Millisecond})
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait %d: %v", i, err)
+		}
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("two requests allowed within %s, want the second held back 100ms", waited)
+	}
+}
+
+func TestRateLimiterWaitPastDeadline(t *testing.T) {
+	l := newRateLimiter(rateLimit{1, time.Hour})
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Wait for a slot an hour away succeeded within a minute's deadline")
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Wait failed after %s, want at once", waited)
+	}
+	// The slot it would have had is given back.
+	if len(l.slots) != 1 {
+		t.Errorf("limiter holds %d slots, want just the first", len(l.slots))
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	l := newRateLimiter(rateLimit{0, time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := l.Wait(ctx); err != nil {
+		t.Errorf("Wait without limits: %v", err)
+	}
+	cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Wait with a cancelled context succeeded")
+	}
+}
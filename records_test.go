@@ -0,0 +1,71 @@
+package satfetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRecordServer returns a client of a server answering every query with
+// the JSON records resp, and checking it was asked for them as JSON.
+func newRecordServer(t *testing.T, resp string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/format/json/metadata/false") {
+			t.Errorf("queried %s, want JSON without metadata", r.URL.Path)
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL+"/ajaxauth/login", srv.URL+"/basicspacedata", "user", "pass")
+	client.SetRateLimits(0, 0)
+	return client
+}
+
+func TestQueryRecords(t *testing.T) {
+	client := newRecordServer(t, `[
+		{"NORAD_CAT_ID": "25544", "OBJECT_NAME": "ISS (ZARYA)", "INTLDES": "1998-067A",
+		 "DECAY_EPOCH": "2030-01-01 00:00:00", "MSG_TYPE": "Prediction", "COUNTRY": null}
+	]`)
+	now := time.Now()
+	decays, err := client.FetchDecays(now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("FetchDecays: %v", err)
+	}
+	want := Decay{NORADID: "25544", ObjectName: "ISS (ZARYA)", IntlDes: "1998-067A",
+		DecayEpoch: "2030-01-01 00:00:00", MessageType: "Prediction"}
+	if len(decays) != 1 || decays[0] != want {
+		t.Errorf("FetchDecays = %+v, want [%+v]", decays, want)
+	}
+
+	// Anything but a list of records is an error.
+	if _, err := newRecordServer(t, `{"error": "nope"}`).FetchBoxscore(); err == nil {
+		t.Error("FetchBoxscore of an error object succeeded")
+	}
+}
+
+func TestFetchConjunctionsUnknownValues(t *testing.T) {
+	client := newRecordServer(t, `[
+		{"CDM_ID": "1", "TCA": "2030-01-01T00:00:00.000000", "MIN_RNG": "120", "PC": "0.0001",
+		 "SAT_1_ID": "25544", "SAT_2_ID": "5"},
+		{"CDM_ID": "2", "TCA": "2030-01-02T00:00:00.000000", "MIN_RNG": null, "PC": "",
+		 "SAT_1_ID": "25544", "SAT_2_ID": "28129"}
+	]`)
+	now := time.Now()
+	conjunctions, err := client.FetchConjunctions(now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("FetchConjunctions: %v", err)
+	}
+	if len(conjunctions) != 2 {
+		t.Fatalf("FetchConjunctions returned %d conjunctions, want 2", len(conjunctions))
+	}
+	if c := conjunctions[0]; c.MissDistanceM == nil || *c.MissDistanceM != 120 || c.Probability == nil || *c.Probability != 0.0001 {
+		t.Errorf("CDM 1 = %+v, want a miss distance of 120 m and a probability of 0.0001", c)
+	}
+	// A row Space Track leaves values out of is still reported, unknown.
+	if c := conjunctions[1]; c.MissDistanceM != nil || c.Probability != nil {
+		t.Errorf("CDM 2 = %+v, want no miss distance or probability", c)
+	}
+}
@@ -0,0 +1,171 @@
+package satfetch
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LatLonBox is a region of the ground bounded by lines of latitude and
+// longitude, in degrees. A box with MinLon greater than MaxLon crosses the
+// antimeridian.
+type LatLonBox struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+}
+
+// Contains reports whether the point at lat, lon is in the box.
+func (b LatLonBox) Contains(lat float64, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// DistanceKm returns how far the point at lat, lon is from the nearest
+// point of the box along the ground, taking the Earth to be a sphere, or 0
+// if the box contains it.
+func (b LatLonBox) DistanceKm(lat float64, lon float64) float64 {
+	if b.Contains(lat, lon) {
+		return 0
+	}
+	clamp := func(lat float64) float64 { return math.Max(b.MinLat, math.Min(b.MaxLat, lat)) }
+	if b.MinLon <= b.MaxLon && lon >= b.MinLon && lon <= b.MaxLon ||
+		b.MinLon > b.MaxLon && (lon >= b.MinLon || lon <= b.MaxLon) {
+		// Due north or south of the box, nearest its edge along a parallel.
+		return groundDistanceKm(lat, lon, clamp(lat), lon)
+	}
+
+	// Otherwise it's nearest one of the edges along meridians, either at a
+	// corner or where the great circle through the point meeting the
+	// meridian at right angles does.
+	dist := math.Inf(1)
+	for _, edgeLon := range []float64{b.MinLon, b.MaxLon} {
+		edgeLats := []float64{b.MinLat, b.MaxLat}
+		if cosDLon := math.Cos((lon - edgeLon) * math.Pi / 180); cosDLon > 0 {
+			edgeLats = append(edgeLats, clamp(math.Atan(math.Tan(lat*math.Pi/180)/cosDLon)*180/math.Pi))
+		}
+		for _, edgeLat := range edgeLats {
+			dist = math.Min(dist, groundDistanceKm(lat, lon, edgeLat, edgeLon))
+		}
+	}
+	return dist
+}
+
+// groundDistanceKm returns the great-circle distance between two points
+// given in degrees on a sphere the Earth's mean radius.
+func groundDistanceKm(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	sinDLat := math.Sin((lat2 - lat1) * rad / 2)
+	sinDLon := math.Sin((lon2 - lon1) * rad / 2)
+	h := sinDLat*sinDLat + math.Cos(lat1*rad)*math.Cos(lat2*rad)*sinDLon*sinDLon
+	return 2 * meanEarthRadiusKm * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+// meanEarthRadiusKm is the radius of the sphere ground distances and
+// footprints are worked out on.
+const meanEarthRadiusKm = 6371.0
+
+// FootprintRadiusKm returns the radius, along the ground, of the circle
+// around the satellite's sub-point at t within which it's at least
+// minElevDeg above the horizon, from its altitude and a spherical Earth.
+func FootprintRadiusKm(tle TLE, t time.Time, minElevDeg float64) (float64, error) {
+	if minElevDeg < 0 || minElevDeg > 90 {
+		return 0, fmt.Errorf("minimum elevation %g° is outside 0° to 90°", minElevDeg)
+	}
+	_, _, altKm, err := SubPoint(tle, t)
+	if err != nil {
+		return 0, err
+	}
+	return footprintRadiusKm(altKm, minElevDeg), nil
+}
+
+// footprintRadiusKm is FootprintRadiusKm for a satellite altKm up.
+func footprintRadiusKm(altKm float64, minElevDeg float64) float64 {
+	if altKm <= 0 {
+		return 0
+	}
+	el := minElevDeg * math.Pi / 180
+	// The angle at the Earth's centre between the sub-point and the edge,
+	// from the triangle it makes with the satellite and a station there.
+	angle := math.Acos(meanEarthRadiusKm/(meanEarthRadiusKm+altKm)*math.Cos(el)) - el
+	return math.Max(angle, 0) * meanEarthRadiusKm
+}
+
+// ParseLatLonBox parses a box written as minLat,minLon,maxLat,maxLon.
+func ParseLatLonBox(s string) (LatLonBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return LatLonBox{}, fmt.Errorf("box %q: want minLat,minLon,maxLat,maxLon", s)
+	}
+	var v [4]float64
+	for i, part := range parts {
+		var err error
+		if v[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return LatLonBox{}, fmt.Errorf("box %q: %v", s, err)
+		}
+	}
+
+	b := LatLonBox{MinLat: v[0], MinLon: v[1], MaxLat: v[2], MaxLon: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLat < -90 || b.MaxLat > 90 {
+		return LatLonBox{}, fmt.Errorf("box %q: latitudes must run from south to north within ±90", s)
+	}
+	if b.MinLon < -180 || b.MinLon > 180 || b.MaxLon < -180 || b.MaxLon > 180 {
+		return LatLonBox{}, fmt.Errorf("box %q: longitudes must be within ±180", s)
+	}
+	return b, nil
+}
+
+// Covers reports whether a satellite altKm above lat, lon is at least
+// minElevDeg above the horizon from somewhere in b: whether its footprint
+// reaches b.
+func (b LatLonBox) Covers(lat float64, lon float64, altKm float64, minElevDeg float64) bool {
+	return b.DistanceKm(lat, lon) <= footprintRadiusKm(altKm, minElevDeg)
+}
+
+// SatellitesOverRegion returns the NORAD IDs, in ascending order, of the
+// satellites whose sub-points are in box at t. Each element set is
+// propagated once; ones that can't be, such as those that have decayed, are
+// left out.
+func SatellitesOverRegion(tles []TLE, box LatLonBox, t time.Time) []uint64 {
+	var over []uint64
+	for _, tle := range tles {
+		lat, lon, _, err := SubPoint(tle, t)
+		if err != nil {
+			slog.Debug("Can't propagate", "norad", tle.NORADID, "err", err)
+			continue
+		}
+		if box.Contains(lat, lon) {
+			over = append(over, tle.NORADID)
+		}
+	}
+	sort.Slice(over, func(i, j int) bool { return over[i] < over[j] })
+	return over
+}
+
+// SatellitesCoveringRegion returns the NORAD IDs, in ascending order, of the
+// satellites at least minElevDeg above the horizon from somewhere in box at
+// t: those whose footprints reach it. It's a coarse filter, on a spherical
+// Earth, for the satellites worth working out look angles for.
+func SatellitesCoveringRegion(tles []TLE, box LatLonBox, t time.Time, minElevDeg float64) []uint64 {
+	var covering []uint64
+	for _, tle := range tles {
+		lat, lon, altKm, err := SubPoint(tle, t)
+		if err != nil {
+			slog.Debug("Can't propagate", "norad", tle.NORADID, "err", err)
+			continue
+		}
+		if box.Covers(lat, lon, altKm, minElevDeg) {
+			covering = append(covering, tle.NORADID)
+		}
+	}
+	sort.Slice(covering, func(i, j int) bool { return covering[i] < covering[j] })
+	return covering
+}
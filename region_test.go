@@ -0,0 +1,45 @@
+package satfetch
+
+import "testing"
+
+func TestLatLonBox(t *testing.T) {
+	britain, err := ParseLatLonBox("50,-6,59,2")
+	if err != nil {
+		t.Fatalf("ParseLatLonBox: %v", err)
+	}
+	pacific, err := ParseLatLonBox("10,170,20,-170")
+	if err != nil {
+		t.Fatalf("ParseLatLonBox: %v", err)
+	}
+
+	// A satellite 400 km up is 10° above the horizon out to about 1340 km
+	// along the ground.
+	tests := []struct {
+		name     string
+		box      LatLonBox
+		lat, lon float64
+		contains bool
+		covers   bool
+	}{
+		{"inside", britain, 52, 0, true, true},
+		{"556 km south", britain, 45, 0, false, true},
+		{"2224 km south", britain, 30, 0, false, false},
+		{"on the antimeridian", pacific, 15, 180, true, true},
+		{"across the antimeridian", pacific, 15, -175, true, true},
+		{"far side of the Earth", pacific, 15, 0, false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.box.Contains(tt.lat, tt.lon); got != tt.contains {
+			t.Errorf("%s: Contains(%g, %g) = %v, want %v", tt.name, tt.lat, tt.lon, got, tt.contains)
+		}
+		if got := tt.box.Covers(tt.lat, tt.lon, 400, 10); got != tt.covers {
+			t.Errorf("%s: Covers(%g, %g, 400, 10) = %v, want %v", tt.name, tt.lat, tt.lon, got, tt.covers)
+		}
+	}
+
+	for _, s := range []string{"50,-6,59", "59,-6,50,2", "50,-6,59,200", "a,b,c,d"} {
+		if _, err := ParseLatLonBox(s); err == nil {
+			t.Errorf("ParseLatLonBox(%q) succeeded", s)
+		}
+	}
+}
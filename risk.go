@@ -0,0 +1,39 @@
+package satfetch
+
+import (
+	"math"
+	"sort"
+)
+
+// DecayRisk scores how soon the satellite an element set describes is
+// likely to reenter, higher being sooner. It's a heuristic, not a
+// propagator: the atmosphere's density falls off with height, so the score
+// drops e-fold for every 50 km of perigee above 200 km, and it grows with
+// the BSTAR drag term, which is larger for objects the drag slows more.
+func DecayRisk(tle TLE) float64 {
+	return math.Exp(-(tle.PerigeeKm()-200)/50) * (1 + 1e4*math.Max(tle.BSTAR, 0))
+}
+
+// RankByDecayRisk returns the latest element set of each satellite in tles,
+// riskiest first by DecayRisk.
+func RankByDecayRisk(tles []TLE) []TLE {
+	latest := make(map[uint64]TLE)
+	for _, tle := range tles {
+		if prev, ok := latest[tle.NORADID]; !ok || tle.EpochTime().After(prev.EpochTime()) {
+			latest[tle.NORADID] = tle
+		}
+	}
+
+	ranked := make([]TLE, 0, len(latest))
+	for _, tle := range latest {
+		ranked = append(ranked, tle)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		ri, rj := DecayRisk(ranked[i]), DecayRisk(ranked[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return ranked[i].NORADID < ranked[j].NORADID
+	})
+	return ranked
+}
@@ -0,0 +1,423 @@
+package satfetch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SatcatFilter selects SATCAT rows. Empty fields match everything.
+type SatcatFilter struct {
+	ObjectType  []string // e.g. PAYLOAD, ROCKET BODY, DEBRIS
+	Country     []string // Space Track owner codes, e.g. US, CIS, PRC
+	OnOrbitOnly bool     // drop objects with a decay date
+	// MaxPerigee drops objects whose perigee is unknown or above this many
+	// km. Zero means no limit.
+	MaxPerigee float64
+}
+
+// FilterSatcat returns the rows that satisfy every criterion in f, in their
+// original order. String comparisons ignore case.
+func FilterSatcat(rows []SatcatRow, f SatcatFilter) []SatcatRow {
+	var filtered []SatcatRow
+	for _, row := range rows {
+		if len(f.ObjectType) > 0 && !containsFold(f.ObjectType, row.ObjectType) {
+			continue
+		}
+		if len(f.Country) > 0 && !containsFold(f.Country, row.Country) {
+			continue
+		}
+		if f.OnOrbitOnly && row.DecayDate != "" {
+			continue
+		}
+		if f.MaxPerigee > 0 {
+			typed, err := row.Typed()
+			if err != nil || typed.Perigee == nil || *typed.Perigee > f.MaxPerigee {
+				continue
+			}
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered
+}
+
+// String describes the filter, or is empty if it matches everything.
+func (f SatcatFilter) String() string {
+	var parts []string
+	if len(f.ObjectType) > 0 {
+		parts = append(parts, "object type "+strings.Join(f.ObjectType, "|"))
+	}
+	if len(f.Country) > 0 {
+		parts = append(parts, "country "+strings.Join(f.Country, "|"))
+	}
+	if f.OnOrbitOnly {
+		parts = append(parts, "on orbit")
+	}
+	if f.MaxPerigee > 0 {
+		parts = append(parts, fmt.Sprintf("perigee up to %g km", f.MaxPerigee))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SatcatIndex looks up SATCAT rows by NORAD ID, international designator
+// and name without scanning the whole catalog for each.
+type SatcatIndex struct {
+	rows     []SatcatRow
+	byNORAD  map[string]int
+	byLaunch map[string][]int // by designator without its piece, e.g. 1998-067
+	names    []string         // lower case SatName and ObjectName, by row
+}
+
+// NewSatcatIndex indexes rows. Lookups return rows in their original order.
+func NewSatcatIndex(rows []SatcatRow) *SatcatIndex {
+	x := &SatcatIndex{
+		rows:     rows,
+		byNORAD:  make(map[string]int, len(rows)),
+		byLaunch: make(map[string][]int),
+		names:    make([]string, len(rows)),
+	}
+	for i, row := range rows {
+		x.byNORAD[normalizeNORADID(row.NORADID)] = i
+		if len(row.IntlDes) >= 8 {
+			launch := row.IntlDes[:8]
+			x.byLaunch[launch] = append(x.byLaunch[launch], i)
+		}
+		x.names[i] = strings.ToLower(row.SatName + "\n" + row.ObjectName)
+	}
+	return x
+}
+
+// ByNORAD returns the row for a NORAD ID, ignoring leading zeros.
+func (x *SatcatIndex) ByNORAD(id string) (SatcatRow, bool) {
+	i, ok := x.byNORAD[normalizeNORADID(id)]
+	if !ok {
+		return SatcatRow{}, false
+	}
+	return x.rows[i], true
+}
+
+// ByIntlDes returns the rows whose designator is des or, if des has no piece
+// letter, e.g. 1998-067, every row from that launch.
+func (x *SatcatIndex) ByIntlDes(des string) []SatcatRow {
+	if len(des) < 8 {
+		return nil
+	}
+	var rows []SatcatRow
+	for _, i := range x.byLaunch[des[:8]] {
+		if len(des) == 8 || x.rows[i].IntlDes == des {
+			rows = append(rows, x.rows[i])
+		}
+	}
+	return rows
+}
+
+// ByName returns the rows whose name contains substr, ignoring case.
+func (x *SatcatIndex) ByName(substr string) []SatcatRow {
+	substr = strings.ToLower(substr)
+	var rows []SatcatRow
+	for i, name := range x.names {
+		if strings.Contains(name, substr) {
+			rows = append(rows, x.rows[i])
+		}
+	}
+	return rows
+}
+
+// ByExactName returns the rows whose SatName or ObjectName is name,
+// ignoring case.
+func (x *SatcatIndex) ByExactName(name string) []SatcatRow {
+	var rows []SatcatRow
+	for _, row := range x.rows {
+		if strings.EqualFold(row.SatName, name) || strings.EqualFold(row.ObjectName, name) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// normalizeNORADID strips leading zeros so that 00005 and 5 match.
+func normalizeNORADID(id string) string {
+	if trimmed := strings.TrimLeft(strings.TrimSpace(id), "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+// TypedSatcatRow is a SatcatRow with its numeric and date fields parsed.
+// Fields that are empty in the catalog are nil or the zero time.
+type TypedSatcatRow struct {
+	IntlDes     string    `json:"intldes"`
+	NORADID     int       `json:"noradid"`
+	ObjectType  string    `json:"objectType"`
+	SatName     string    `json:"satName"`
+	Country     string    `json:"country"`
+	LaunchDate  time.Time `json:"launchDate"`
+	LaunchSite  string    `json:"launchSite"`
+	DecayDate   time.Time `json:"decayDate"`
+	Period      *float64  `json:"period,omitempty"`      // minutes
+	Inclination *float64  `json:"inclination,omitempty"` // degrees
+	Apogee      *float64  `json:"apogee,omitempty"`      // km
+	Perigee     *float64  `json:"perigee,omitempty"`     // km
+}
+
+// SatcatDateLayout is how Space Track writes SATCAT launch and decay dates.
+const SatcatDateLayout = "2006-01-02"
+
+// Typed parses the row's numeric and date fields.
+func (r SatcatRow) Typed() (TypedSatcatRow, error) {
+	t := TypedSatcatRow{
+		IntlDes:    r.IntlDes,
+		ObjectType: r.ObjectType,
+		SatName:    r.SatName,
+		Country:    r.Country,
+		LaunchSite: r.LaunchSite,
+	}
+
+	var err error
+	if t.NORADID, err = strconv.Atoi(r.NORADID); err != nil {
+		return t, fmt.Errorf("NORAD ID %q: %v", r.NORADID, err)
+	}
+	if t.LaunchDate, err = parseSatcatDate(r.LaunchDate); err != nil {
+		return t, fmt.Errorf("%s: launch date: %v", r.NORADID, err)
+	}
+	if t.DecayDate, err = parseSatcatDate(r.DecayDate); err != nil {
+		return t, fmt.Errorf("%s: decay date: %v", r.NORADID, err)
+	}
+
+	for _, f := range []struct {
+		name  string
+		value string
+		dest  **float64
+	}{
+		{"period", r.Period, &t.Period},
+		{"inclination", r.Inclination, &t.Inclination},
+		{"apogee", r.Apogeee, &t.Apogee},
+		{"perigee", r.Perigee, &t.Perigee},
+	} {
+		if f.value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return t, fmt.Errorf("%s: %s: %v", r.NORADID, f.name, err)
+		}
+		*f.dest = &v
+	}
+
+	return t, nil
+}
+
+// parseSatcatDate parses a SATCAT date, returning the zero time if empty.
+func parseSatcatDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(SatcatDateLayout, s)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSATCATCSV reads a SATCAT in CSV format and returns a slice of SatcatRows.
+// Columns are matched to fields by the names in the header row, so their
+// order doesn't matter. Unknown columns are ignored, and fields whose column
+// is missing are left empty.
+func ParseSATCATCSV(filename string) ([]SatcatRow, error) {
+	file, err := OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	satcatRows, err := ReadSATCATCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return satcatRows, nil
+}
+
+// WriteSATCATCSV writes rows as CSV, headed by SatcatRow's json tags, which
+// ParseSATCATCSV reads back.
+func WriteSATCATCSV(w io.Writer, rows []SatcatRow) error {
+	t := reflect.TypeOf(SatcatRow{})
+	record := make([]string, t.NumField())
+	for i := range record {
+		record[i] = t.Field(i).Tag.Get("json")
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		for i := range record {
+			record[i] = v.Field(i).String()
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadSATCATCSV is like ParseSATCATCSV but reads the SATCAT from r.
+func ReadSATCATCSV(r io.Reader) ([]SatcatRow, error) {
+	csvReader := csv.NewReader(r)
+	var satcatRows []SatcatRow
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// fields[i] locates the SatcatRow field for column i, or is nil if the
+	// column isn't one we know.
+	fields := make([]func(*SatcatRow) *string, len(header))
+	for i, name := range header {
+		fields[i] = satcatField(name)
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var satcatRow SatcatRow
+		for i, value := range record {
+			if fields[i] != nil {
+				*fields[i](&satcatRow) = value
+			}
+		}
+		satcatRows = append(satcatRows, satcatRow)
+	}
+
+	return satcatRows, nil
+}
+
+// ParseSATCATJSON decodes a SATCAT in Space Track's JSON format, an array of
+// objects keyed by the same names as the CSV columns. Objects keyed by
+// SatcatRow's own json tags are accepted too.
+func ParseSATCATJSON(r io.Reader) ([]SatcatRow, error) {
+	var records []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, err
+	}
+
+	satcatRows := make([]SatcatRow, len(records))
+	for i, record := range records {
+		for name, value := range record {
+			field := satcatField(name)
+			if field == nil {
+				continue
+			}
+			// Space Track sends every value as a string, or null if empty.
+			switch v := value.(type) {
+			case string:
+				*field(&satcatRows[i]) = v
+			case float64:
+				*field(&satcatRows[i]) = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+
+	return satcatRows, nil
+}
+
+// satcatField returns the accessor for the SatcatRow field stored under
+// name, either a Space Track column name or one of SatcatRow's json tags,
+// ignoring case. It returns nil if there's no such field.
+func satcatField(name string) func(*SatcatRow) *string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if field, ok := satcatColumns[name]; ok {
+		return field
+	}
+
+	t := reflect.TypeOf(SatcatRow{})
+	for i := 0; i < t.NumField(); i++ {
+		if strings.ToUpper(t.Field(i).Tag.Get("json")) == name {
+			index := i
+			return func(r *SatcatRow) *string {
+				return reflect.ValueOf(r).Elem().Field(index).Addr().Interface().(*string)
+			}
+		}
+	}
+
+	return nil
+}
+
+// satcatColumns maps Space Track's SATCAT column names to SatcatRow fields.
+var satcatColumns = map[string]func(*SatcatRow) *string{
+	"INTLDES":       func(r *SatcatRow) *string { return &r.IntlDes },
+	"NORAD_CAT_ID":  func(r *SatcatRow) *string { return &r.NORADID },
+	"OBJECT_TYPE":   func(r *SatcatRow) *string { return &r.ObjectType },
+	"SATNAME":       func(r *SatcatRow) *string { return &r.SatName },
+	"COUNTRY":       func(r *SatcatRow) *string { return &r.Country },
+	"LAUNCH":        func(r *SatcatRow) *string { return &r.LaunchDate },
+	"SITE":          func(r *SatcatRow) *string { return &r.LaunchSite },
+	"DECAY":         func(r *SatcatRow) *string { return &r.DecayDate },
+	"PERIOD":        func(r *SatcatRow) *string { return &r.Period },
+	"INCLINATION":   func(r *SatcatRow) *string { return &r.Inclination },
+	"APOGEE":        func(r *SatcatRow) *string { return &r.Apogeee },
+	"PERIGEE":       func(r *SatcatRow) *string { return &r.Perigee },
+	"COMMENT":       func(r *SatcatRow) *string { return &r.Comment },
+	"COMMENTCODE":   func(r *SatcatRow) *string { return &r.CommentCode },
+	"RCSVALUE":      func(r *SatcatRow) *string { return &r.RCSValue },
+	"RCS_SIZE":      func(r *SatcatRow) *string { return &r.RCSSize },
+	"FILE":          func(r *SatcatRow) *string { return &r.FileID },
+	"LAUNCH_YEAR":   func(r *SatcatRow) *string { return &r.LaunchYear },
+	"LAUNCH_NUM":    func(r *SatcatRow) *string { return &r.LaunchNum },
+	"LAUNCH_PIECE":  func(r *SatcatRow) *string { return &r.LaunchPiece },
+	"CURRENT":       func(r *SatcatRow) *string { return &r.IsCurrent },
+	"OBJECT_NAME":   func(r *SatcatRow) *string { return &r.ObjectName },
+	"OBJECT_ID":     func(r *SatcatRow) *string { return &r.ObjectID },
+	"OBJECT_NUMBER": func(r *SatcatRow) *string { return &r.ObjectNum },
+}
+
+// SatcatRow respresents a row of the Space Track satellite catalog.
+type SatcatRow struct {
+	IntlDes     string `json:"intldes"`
+	NORADID     string `json:"noradid"`
+	ObjectType  string `json:"objectType"`
+	SatName     string `json:"satName"`
+	Country     string `json:"country"`
+	LaunchDate  string `json:"launchDate"`
+	LaunchSite  string `json:"launchSite"`
+	DecayDate   string `json:"decayDate"`
+	Period      string `json:"period"`
+	Inclination string `json:"inclination"`
+	Apogeee     string `json:"apogee"`
+	Perigee     string `json:"perigee"`
+	Comment     string `json:"comment"`
+	CommentCode string `json:"commentCode"`
+	RCSValue    string `json:"rcsValue"`
+	RCSSize     string `json:"rcsSize"`
+	FileID      string `json:"fileID"`
+	LaunchYear  string `json:"launchYear"`
+	LaunchNum   string `json:"launchNum"`
+	LaunchPiece string `json:"launchPiece"`
+	IsCurrent   string `json:"isCurrent"`
+	ObjectName  string `json:"objectName"`
+	ObjectID    string `json:"objectID"`
+	ObjectNum   string `json:"objectNum"`
+}
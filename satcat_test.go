@@ -0,0 +1,239 @@
+package satfetch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTemp writes data to a file named name in a temporary directory and
+// returns its path.
+func writeTemp(t *testing.T, name string, data string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestParseSATCATCSVColumns(t *testing.T) {
+	// SATNAME comes first, RCS_SIZE is missing, and COLOR is unknown.
+	csv := "SATNAME,COLOR,DECAY,NORAD_CAT_ID,INTLDES,OBJECT_TYPE,COUNTRY,LAUNCH\n" +
+		"ISS (ZARYA),white,,25544,1998-067A,PAYLOAD,ISS,1998-11-20\n" +
+		"SL-1 R/B,black,1957-12-01,1,1957-001A,ROCKET BODY,CIS,1957-10-04\n"
+
+	rows, err := ParseSATCATCSV(writeTemp(t, "satcat.csv", csv))
+	if err != nil {
+		t.Fatalf("ParseSATCATCSV: %v", err)
+	}
+	want := []SatcatRow{
+		{SatName: "ISS (ZARYA)", NORADID: "25544", IntlDes: "1998-067A", ObjectType: "PAYLOAD", Country: "ISS", LaunchDate: "1998-11-20"},
+		{SatName: "SL-1 R/B", DecayDate: "1957-12-01", NORADID: "1", IntlDes: "1957-001A", ObjectType: "ROCKET BODY", Country: "CIS", LaunchDate: "1957-10-04"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("ParseSATCATCSV returned %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d =\n%+v\nwant\n%+v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestParseSATCATCSVEmpty(t *testing.T) {
+	if _, err := ParseSATCATCSV(writeTemp(t, "satcat.csv", "")); err == nil || !strings.Contains(err.Error(), "missing header row") {
+		t.Errorf("ParseSATCATCSV of nothing: error = %v, want a missing header row", err)
+	}
+}
+
+func TestParseSATCATCSV(t *testing.T) {
+	rows, err := ParseSATCATCSV("testdata/satcat.csv")
+	if err != nil {
+		t.Fatalf("ParseSATCATCSV: %v", err)
+	}
+	if len(rows) != 4 || rows[2].NORADID != "25544" || rows[2].RCSSize != "LARGE" {
+		t.Errorf("ParseSATCATCSV = %+v, want the four rows with the ISS third", rows)
+	}
+}
+
+func TestParseSATCATJSON(t *testing.T) {
+	f, err := os.Open("testdata/satcat.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := ParseSATCATJSON(f)
+	if err != nil {
+		t.Fatalf("ParseSATCATJSON: %v", err)
+	}
+
+	// The JSON holds the same objects as the CSV, with nulls for the empty
+	// values.
+	want, err := ParseSATCATCSV("testdata/satcat.csv")
+	if err != nil {
+		t.Fatalf("ParseSATCATCSV: %v", err)
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("ParseSATCATJSON returned %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d =\n%+v\nwant\n%+v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestParseSATCATJSONKeys(t *testing.T) {
+	// SatcatRow's own keys, and numbers where Space Track sends strings.
+	rows, err := ParseSATCATJSON(strings.NewReader(`[{"noradid": "25544", "satName": "ISS (ZARYA)", "PERIOD": 92.9}]`))
+	if err != nil {
+		t.Fatalf("ParseSATCATJSON: %v", err)
+	}
+	want := SatcatRow{NORADID: "25544", SatName: "ISS (ZARYA)", Period: "92.9"}
+	if len(rows) != 1 || rows[0] != want {
+		t.Errorf("ParseSATCATJSON = %+v, want [%+v]", rows, want)
+	}
+}
+
+func TestFilterSatcat(t *testing.T) {
+	rows, err := ParseSATCATCSV("testdata/satcat.csv")
+	if err != nil {
+		t.Fatalf("ParseSATCATCSV: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter SatcatFilter
+		want   []string // NORAD IDs
+	}{
+		{"everything", SatcatFilter{}, []string{"1", "5", "25544", "28129"}},
+		{"object type", SatcatFilter{ObjectType: []string{"ROCKET BODY"}}, []string{"1"}},
+		{"object type ignores case", SatcatFilter{ObjectType: []string{"payload"}}, []string{"5", "25544", "28129"}},
+		{"countries", SatcatFilter{Country: []string{"CIS", "ISS"}}, []string{"1", "25544"}},
+		{"on orbit", SatcatFilter{OnOrbitOnly: true}, []string{"5", "25544", "28129"}},
+		{"every criterion", SatcatFilter{ObjectType: []string{"PAYLOAD"}, Country: []string{"us"}, OnOrbitOnly: true}, []string{"5", "28129"}},
+		{"perigee", SatcatFilter{MaxPerigee: 650}, []string{"1", "5", "25544"}},
+		{"no match", SatcatFilter{Country: []string{"PRC"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, row := range FilterSatcat(rows, tt.filter) {
+				got = append(got, row.NORADID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterSatcat(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSatcatRowTyped(t *testing.T) {
+	row := SatcatRow{
+		IntlDes: "1957-001A", NORADID: "1", ObjectType: "ROCKET BODY", SatName: "SL-1 R/B", Country: "CIS",
+		LaunchDate: "1957-10-04", LaunchSite: "TYMSC", DecayDate: "1957-12-01",
+		Period: "96.19", Inclination: "65.10", Apogeee: "938",
+	}
+	got, err := row.Typed()
+	if err != nil {
+		t.Fatalf("Typed: %v", err)
+	}
+	if got.NORADID != 1 || got.SatName != "SL-1 R/B" || got.LaunchSite != "TYMSC" {
+		t.Errorf("Typed = %+v", got)
+	}
+	if want := time.Date(1957, 10, 4, 0, 0, 0, 0, time.UTC); !got.LaunchDate.Equal(want) {
+		t.Errorf("LaunchDate = %v, want %v", got.LaunchDate, want)
+	}
+	if want := time.Date(1957, 12, 1, 0, 0, 0, 0, time.UTC); !got.DecayDate.Equal(want) {
+		t.Errorf("DecayDate = %v, want %v", got.DecayDate, want)
+	}
+	if got.Period == nil || *got.Period != 96.19 || got.Apogee == nil || *got.Apogee != 938 {
+		t.Errorf("Period, Apogee = %v, %v, want 96.19, 938", got.Period, got.Apogee)
+	}
+	if got.Perigee != nil {
+		t.Errorf("Perigee = %v for an empty field, want nil", *got.Perigee)
+	}
+
+	row.DecayDate = ""
+	if got, err := row.Typed(); err != nil || !got.DecayDate.IsZero() {
+		t.Errorf("Typed with no decay date: DecayDate = %v, error %v; want the zero time", got.DecayDate, err)
+	}
+}
+
+func TestSatcatRowTypedErrors(t *testing.T) {
+	valid := SatcatRow{NORADID: "25544", LaunchDate: "1998-11-20", Period: "92.90", Perigee: "415"}
+	tests := []struct {
+		name string
+		edit func(*SatcatRow)
+		want string // in the error
+	}{
+		{"NORAD ID", func(r *SatcatRow) { r.NORADID = "ISS" }, `NORAD ID "ISS"`},
+		{"launch date", func(r *SatcatRow) { r.LaunchDate = "20/11/1998" }, "25544: launch date: "},
+		{"decay date", func(r *SatcatRow) { r.DecayDate = "1998-13-01" }, "25544: decay date: "},
+		{"period", func(r *SatcatRow) { r.Period = "92.9x" }, "25544: period: "},
+		{"inclination", func(r *SatcatRow) { r.Inclination = "fifty" }, "25544: inclination: "},
+		{"apogee", func(r *SatcatRow) { r.Apogeee = "4 22" }, "25544: apogee: "},
+		{"perigee", func(r *SatcatRow) { r.Perigee = "-" }, "25544: perigee: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := valid
+			tt.edit(&row)
+			_, err := row.Typed()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Typed() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := valid.Typed(); err != nil {
+		t.Errorf("Typed() of a valid row: %v", err)
+	}
+}
+
+func TestSatcatIndex(t *testing.T) {
+	rows, err := ParseSATCATCSV("testdata/satcat.csv")
+	if err != nil {
+		t.Fatalf("ParseSATCATCSV: %v", err)
+	}
+	rows = append(rows, SatcatRow{NORADID: "25545", IntlDes: "1998-067B", SatName: "ISS DEB", ObjectName: "ISS DEB"})
+	x := NewSatcatIndex(rows)
+
+	for _, id := range []string{"25544", "025544", " 25544"} {
+		row, ok := x.ByNORAD(id)
+		if !ok || row.SatName != "ISS (ZARYA)" {
+			t.Errorf("ByNORAD(%q) = %+v, %t, want the ISS", id, row, ok)
+		}
+	}
+	for _, id := range []string{"25546", "0", "", "ISS"} {
+		if row, ok := x.ByNORAD(id); ok {
+			t.Errorf("ByNORAD(%q) = %+v, want no row", id, row)
+		}
+	}
+
+	lookups := []struct {
+		name string
+		got  []SatcatRow
+		want []string // NORAD IDs
+	}{
+		{"ByIntlDes piece", x.ByIntlDes("1998-067A"), []string{"25544"}},
+		{"ByIntlDes launch", x.ByIntlDes("1998-067"), []string{"25544", "25545"}},
+		{"ByIntlDes missing piece", x.ByIntlDes("1998-067C"), nil},
+		{"ByIntlDes missing launch", x.ByIntlDes("1998-068"), nil},
+		{"ByIntlDes short", x.ByIntlDes("1998"), nil},
+		{"ByName", x.ByName("iss"), []string{"25544", "25545"}},
+		{"ByName missing", x.ByName("hubble"), nil},
+	}
+	for _, l := range lookups {
+		var got []string
+		for _, row := range l.got {
+			got = append(got, row.NORADID)
+		}
+		if !reflect.DeepEqual(got, l.want) {
+			t.Errorf("%s = %v, want %v", l.name, got, l.want)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package satfetch
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// WGS-72 constants, which SGP4 element sets are generated against.
+const (
+	earthRadiusKm = 6378.135
+	earthMu       = 398600.8 // km³/s²
+	j2            = 0.001082616
+	j3            = -0.00000253881
+	j4            = -0.00000165597
+	j3oj2         = j3 / j2
+	twoPi         = 2 * math.Pi
+	x2o3          = 2.0 / 3.0
+)
+
+// xke is the square root of earthMu in earth radii³ per minute².
+var xke = 60 / math.Sqrt(earthRadiusKm*earthRadiusKm*earthRadiusKm/earthMu)
+
+// PropagateTEME runs the TLE through SGP4, or SDP4 for periods of 225
+// minutes or more, returning the position in km and velocity in km/s at t in
+// the True Equator Mean Equinox frame.
+func (tle TLE) PropagateTEME(t time.Time) (pos [3]float64, vel [3]float64, err error) {
+	s, err := newSGP4(tle)
+	if err != nil {
+		return pos, vel, err
+	}
+	return s.propagate(t.Sub(tle.EpochTime()).Minutes())
+}
+
+// StateAtEpoch returns the TEME position in km and velocity in km/s at the
+// element set's own epoch, the initial condition other propagators want.
+// The mean elements are SGP4's, so this is not their osculating state. The
+// reverse, fitting an element set to a state vector, is out of scope: it
+// takes a least squares fit over many states, not a conversion.
+func (tle TLE) StateAtEpoch() (pos [3]float64, vel [3]float64, err error) {
+	s, err := newSGP4(tle)
+	if err != nil {
+		return pos, vel, err
+	}
+	return s.propagate(0)
+}
+
+// sgp4 holds the initialized state of a propagation. The names follow
+// Vallado's reference implementation (Revisiting Spacetrack Report #3,
+// AIAA 2006-6753) so the two can be compared line by line.
+type sgp4 struct {
+	// Mean elements at epoch. Angles are in radians, no in radians/minute.
+	bstar, ecco, argpo, inclo, mo, no, nodeo float64
+
+	deepSpace bool
+	isimp     bool
+
+	aycof, con41, cc1, cc4, cc5, d2, d3, d4, delmo, eta, argpdot, omgcof,
+	sinmao, t2cof, t3cof, t4cof, t5cof, x1mth2, x7thm1, mdot, nodedot, xlcof,
+	xmcof, nodecf float64
+
+	// Deep space terms.
+	irez                                                          int
+	d2201, d2211, d3210, d3222, d4410, d4422, d5220, d5232, d5421 float64
+	d5433, dedt, del1, del2, del3, didt, dmdt, dnodt, domdt       float64
+	e3, ee2, se2, se3, sgh2, sgh3                                 float64
+	sgh4, sh2, sh3, si2, si3, sl2, sl3, sl4, gsto, xfact, xgh2    float64
+	xgh3, xgh4, xh2, xh3, xi2, xi3, xl2, xl3, xl4, xlamo, zmol    float64
+	zmos                                                          float64
+}
+
+// newSGP4 initializes a propagation from the TLE's mean elements.
+func newSGP4(tle TLE) (*sgp4, error) {
+	deg := math.Pi / 180
+	s := &sgp4{
+		bstar: tle.BSTAR,
+		ecco:  float64(tle.Eccentricity),
+		argpo: float64(tle.ArgOfPerigee) * deg,
+		inclo: float64(tle.Inclination) * deg,
+		mo:    float64(tle.MeanAnomaly) * deg,
+		nodeo: float64(tle.RAAN) * deg,
+		no:    tle.MeanMotion * twoPi / 1440,
+	}
+	if s.no <= 0 {
+		return nil, fmt.Errorf("sgp4: mean motion %g rev/day must be positive", tle.MeanMotion)
+	}
+	if s.ecco < 0 || s.ecco >= 1 {
+		return nil, fmt.Errorf("sgp4: eccentricity %g out of range", s.ecco)
+	}
+
+	// Days since 1950 January 0.0 UTC.
+	jdEpoch := julianDate(tle.EpochTime())
+	epoch := jdEpoch - 2433281.5
+
+	// Undo the Kozai mean motion, as initl does.
+	eccsq := s.ecco * s.ecco
+	omeosq := 1 - eccsq
+	rteosq := math.Sqrt(omeosq)
+	cosio := math.Cos(s.inclo)
+	cosio2 := cosio * cosio
+	ak := math.Pow(xke/s.no, x2o3)
+	d1 := 0.75 * j2 * (3*cosio2 - 1) / (rteosq * omeosq)
+	del := d1 / (ak * ak)
+	adel := ak * (1 - del*del - del*(1.0/3.0+134*del*del/81))
+	del = d1 / (adel * adel)
+	s.no = s.no / (1 + del)
+
+	ao := math.Pow(xke/s.no, x2o3)
+	sinio := math.Sin(s.inclo)
+	po := ao * omeosq
+	con42 := 1 - 5*cosio2
+	s.con41 = -con42 - cosio2 - cosio2
+	posq := po * po
+	rp := ao * (1 - s.ecco)
+	s.gsto = gstime(jdEpoch)
+
+	ss := 78/earthRadiusKm + 1
+	qzms2t := math.Pow((120-78)/earthRadiusKm, 4)
+
+	// Perigees below 220 km get the simplified drag model.
+	s.isimp = rp < 220/earthRadiusKm+1
+	sfour := ss
+	qzms24 := qzms2t
+	perige := (rp - 1) * earthRadiusKm
+	if perige < 156 {
+		sfour = perige - 78
+		if perige < 98 {
+			sfour = 20
+		}
+		qzms24 = math.Pow((120-sfour)/earthRadiusKm, 4)
+		sfour = sfour/earthRadiusKm + 1
+	}
+	pinvsq := 1 / posq
+
+	tsi := 1 / (ao - sfour)
+	s.eta = ao * s.ecco * tsi
+	etasq := s.eta * s.eta
+	eeta := s.ecco * s.eta
+	psisq := math.Abs(1 - etasq)
+	coef := qzms24 * math.Pow(tsi, 4)
+	coef1 := coef / math.Pow(psisq, 3.5)
+	cc2 := coef1 * s.no * (ao*(1+1.5*etasq+eeta*(4+etasq)) +
+		0.375*j2*tsi/psisq*s.con41*(8+3*etasq*(8+etasq)))
+	s.cc1 = s.bstar * cc2
+	cc3 := 0.0
+	if s.ecco > 1e-4 {
+		cc3 = -2 * coef * tsi * j3oj2 * s.no * sinio / s.ecco
+	}
+	s.x1mth2 = 1 - cosio2
+	s.cc4 = 2 * s.no * coef1 * ao * omeosq *
+		(s.eta*(2+0.5*etasq) + s.ecco*(0.5+2*etasq) -
+			j2*tsi/(ao*psisq)*
+				(-3*s.con41*(1-2*eeta+etasq*(1.5-0.5*eeta))+
+					0.75*s.x1mth2*(2*etasq-eeta*(1+etasq))*math.Cos(2*s.argpo)))
+	s.cc5 = 2 * coef1 * ao * omeosq * (1 + 2.75*(etasq+eeta) + eeta*etasq)
+	cosio4 := cosio2 * cosio2
+	temp1 := 1.5 * j2 * pinvsq * s.no
+	temp2 := 0.5 * temp1 * j2 * pinvsq
+	temp3 := -0.46875 * j4 * pinvsq * pinvsq * s.no
+	s.mdot = s.no + 0.5*temp1*rteosq*s.con41 + 0.0625*temp2*rteosq*(13-78*cosio2+137*cosio4)
+	s.argpdot = -0.5*temp1*con42 + 0.0625*temp2*(7-114*cosio2+395*cosio4) +
+		temp3*(3-36*cosio2+49*cosio4)
+	xhdot1 := -temp1 * cosio
+	s.nodedot = xhdot1 + (0.5*temp2*(4-19*cosio2)+2*temp3*(3-7*cosio2))*cosio
+	xpidot := s.argpdot + s.nodedot
+	s.omgcof = s.bstar * cc3 * math.Cos(s.argpo)
+	if s.ecco > 1e-4 {
+		s.xmcof = -x2o3 * coef * s.bstar / eeta
+	}
+	s.nodecf = 3.5 * omeosq * xhdot1 * s.cc1
+	s.t2cof = 1.5 * s.cc1
+	s.xlcof = xlcof(sinio, cosio)
+	s.aycof = -0.5 * j3oj2 * sinio
+	s.delmo = math.Pow(1+s.eta*math.Cos(s.mo), 3)
+	s.sinmao = math.Sin(s.mo)
+	s.x7thm1 = 7*cosio2 - 1
+
+	if twoPi/s.no >= 225 {
+		s.deepSpace = true
+		s.isimp = true
+		ds := s.dscom(epoch, s.ecco, s.argpo, 0, s.inclo, s.nodeo, s.no)
+		s.dsinit(ds, xpidot, eccsq)
+	}
+
+	if !s.isimp {
+		cc1sq := s.cc1 * s.cc1
+		s.d2 = 4 * ao * tsi * cc1sq
+		temp := s.d2 * tsi * s.cc1 / 3
+		s.d3 = (17*ao + sfour) * temp
+		s.d4 = 0.5 * temp * ao * tsi * (221*ao + 31*sfour) * s.cc1
+		s.t3cof = s.d2 + 2*cc1sq
+		s.t4cof = 0.25 * (3*s.d3 + s.cc1*(12*s.d2+10*cc1sq))
+		s.t5cof = 0.2 * (3*s.d4 + 12*s.cc1*s.d3 + 6*s.d2*s.d2 + 15*cc1sq*(2*s.d2+cc1sq))
+	}
+
+	return s, nil
+}
+
+// xlcof is the long period periodics coefficient, guarding against
+// division by zero at an inclination of 180°.
+func xlcof(sinio float64, cosio float64) float64 {
+	if math.Abs(cosio+1) > 1.5e-12 {
+		return -0.25 * j3oj2 * sinio * (3 + 5*cosio) / (1 + cosio)
+	}
+	return -0.25 * j3oj2 * sinio * (3 + 5*cosio) / 1.5e-12
+}
+
+// propagate returns the TEME position and velocity t minutes after the
+// element set's epoch.
+func (s *sgp4) propagate(t float64) (pos [3]float64, vel [3]float64, err error) {
+	// Secular gravity and atmospheric drag.
+	xmdf := s.mo + s.mdot*t
+	argpdf := s.argpo + s.argpdot*t
+	nodedf := s.nodeo + s.nodedot*t
+	argpm := argpdf
+	mm := xmdf
+	t2 := t * t
+	nodem := nodedf + s.nodecf*t2
+	tempa := 1 - s.cc1*t
+	tempe := s.bstar * s.cc4 * t
+	templ := s.t2cof * t2
+
+	if !s.isimp {
+		delomg := s.omgcof * t
+		delmtemp := 1 + s.eta*math.Cos(xmdf)
+		delm := s.xmcof * (delmtemp*delmtemp*delmtemp - s.delmo)
+		temp := delomg + delm
+		mm = xmdf + temp
+		argpm = argpdf - temp
+		t3 := t2 * t
+		t4 := t3 * t
+		tempa = tempa - s.d2*t2 - s.d3*t3 - s.d4*t4
+		tempe = tempe + s.bstar*s.cc5*(math.Sin(mm)-s.sinmao)
+		templ = templ + s.t3cof*t3 + t4*(s.t4cof+t*s.t5cof)
+	}
+
+	nm := s.no
+	em := s.ecco
+	inclm := s.inclo
+	if s.deepSpace {
+		em, argpm, inclm, mm, nodem, nm = s.dspace(t, em, argpm, inclm, mm, nodem)
+	}
+
+	if nm <= 0 {
+		return pos, vel, fmt.Errorf("sgp4: mean motion %g is not positive after %g minutes", nm, t)
+	}
+	am := math.Pow(xke/nm, x2o3) * tempa * tempa
+	nm = xke / math.Pow(am, 1.5)
+	em = em - tempe
+
+	if em >= 1 || em < -0.001 {
+		return pos, vel, fmt.Errorf("sgp4: mean eccentricity %g out of range after %g minutes", em, t)
+	}
+	if em < 1e-6 {
+		em = 1e-6
+	}
+	mm = mm + s.no*templ
+	xlm := mm + argpm + nodem
+
+	nodem = math.Mod(nodem, twoPi)
+	argpm = math.Mod(argpm, twoPi)
+	xlm = math.Mod(xlm, twoPi)
+	mm = math.Mod(xlm-argpm-nodem, twoPi)
+
+	// Lunar-solar periodics.
+	ep := em
+	xincp := inclm
+	argpp := argpm
+	nodep := nodem
+	mp := mm
+	sinip := math.Sin(inclm)
+	cosip := math.Cos(inclm)
+	aycof := s.aycof
+	xlcf := s.xlcof
+	con41 := s.con41
+	x1mth2 := s.x1mth2
+	x7thm1 := s.x7thm1
+
+	if s.deepSpace {
+		ep, xincp, nodep, argpp, mp = s.dpper(t, ep, xincp, nodep, argpp, mp)
+		if xincp < 0 {
+			xincp = -xincp
+			nodep = nodep + math.Pi
+			argpp = argpp - math.Pi
+		}
+		if ep < 0 || ep > 1 {
+			return pos, vel, fmt.Errorf("sgp4: perturbed eccentricity %g out of range after %g minutes", ep, t)
+		}
+
+		sinip = math.Sin(xincp)
+		cosip = math.Cos(xincp)
+		aycof = -0.5 * j3oj2 * sinip
+		xlcf = xlcof(sinip, cosip)
+	}
+
+	// Long period periodics.
+	axnl := ep * math.Cos(argpp)
+	temp := 1 / (am * (1 - ep*ep))
+	aynl := ep*math.Sin(argpp) + temp*aycof
+	xl := mp + argpp + nodep + temp*xlcf*axnl
+
+	// Solve Kepler's equation.
+	u := math.Mod(xl-nodep, twoPi)
+	eo1 := u
+	tem5 := 9999.9
+	var sineo1, coseo1 float64
+	for ktr := 1; math.Abs(tem5) >= 1e-12 && ktr <= 10; ktr++ {
+		sineo1 = math.Sin(eo1)
+		coseo1 = math.Cos(eo1)
+		tem5 = 1 - coseo1*axnl - sineo1*aynl
+		tem5 = (u - aynl*coseo1 + axnl*sineo1 - eo1) / tem5
+		if math.Abs(tem5) >= 0.95 {
+			tem5 = math.Copysign(0.95, tem5)
+		}
+		eo1 = eo1 + tem5
+	}
+
+	// Short period preliminary quantities.
+	ecose := axnl*coseo1 + aynl*sineo1
+	esine := axnl*sineo1 - aynl*coseo1
+	el2 := axnl*axnl + aynl*aynl
+	pl := am * (1 - el2)
+	if pl < 0 {
+		return pos, vel, fmt.Errorf("sgp4: semi-latus rectum %g is negative after %g minutes", pl, t)
+	}
+
+	rl := am * (1 - ecose)
+	rdotl := math.Sqrt(am) * esine / rl
+	rvdotl := math.Sqrt(pl) / rl
+	betal := math.Sqrt(1 - el2)
+	temp = esine / (1 + betal)
+	sinu := am / rl * (sineo1 - aynl - axnl*temp)
+	cosu := am / rl * (coseo1 - axnl + aynl*temp)
+	su := math.Atan2(sinu, cosu)
+	sin2u := (cosu + cosu) * sinu
+	cos2u := 1 - 2*sinu*sinu
+	temp = 1 / pl
+	temp1 := 0.5 * j2 * temp
+	temp2 := temp1 * temp
+
+	if s.deepSpace {
+		cosisq := cosip * cosip
+		con41 = 3*cosisq - 1
+		x1mth2 = 1 - cosisq
+		x7thm1 = 7*cosisq - 1
+	}
+
+	// Update for short period periodics.
+	mrt := rl*(1-1.5*temp2*betal*con41) + 0.5*temp1*x1mth2*cos2u
+	su = su - 0.25*temp2*x7thm1*sin2u
+	xnode := nodep + 1.5*temp2*cosip*sin2u
+	xinc := xincp + 1.5*temp2*cosip*sinip*cos2u
+	mvt := rdotl - nm*temp1*x1mth2*sin2u/xke
+	rvdot := rvdotl + nm*temp1*(x1mth2*cos2u+1.5*con41)/xke
+
+	// Orientation vectors.
+	sinsu := math.Sin(su)
+	cossu := math.Cos(su)
+	snod := math.Sin(xnode)
+	cnod := math.Cos(xnode)
+	sini := math.Sin(xinc)
+	cosi := math.Cos(xinc)
+	xmx := -snod * cosi
+	xmy := cnod * cosi
+	ux := xmx*sinsu + cnod*cossu
+	uy := xmy*sinsu + snod*cossu
+	uz := sini * sinsu
+	vx := xmx*cossu - cnod*sinsu
+	vy := xmy*cossu - snod*sinsu
+	vz := sini * cossu
+
+	vkmpersec := earthRadiusKm * xke / 60
+	pos = [3]float64{mrt * ux * earthRadiusKm, mrt * uy * earthRadiusKm, mrt * uz * earthRadiusKm}
+	vel = [3]float64{
+		(mvt*ux + rvdot*vx) * vkmpersec,
+		(mvt*uy + rvdot*vy) * vkmpersec,
+		(mvt*uz + rvdot*vz) * vkmpersec,
+	}
+
+	if mrt < 1 {
+		return pos, vel, fmt.Errorf("sgp4: satellite has decayed after %g minutes", t)
+	}
+	return pos, vel, nil
+}
+
+// julianDate returns the Julian date of t.
+func julianDate(t time.Time) float64 {
+	return float64(t.UnixNano())/86400e9 + 2440587.5
+}
+
+// gstime returns Greenwich sidereal time in radians at the UT1 Julian date
+// jdut1, per IAU-82.
+func gstime(jdut1 float64) float64 {
+	tut1 := (jdut1 - 2451545) / 36525
+	temp := -6.2e-6*tut1*tut1*tut1 + 0.093104*tut1*tut1 +
+		(876600*3600+8640184.812866)*tut1 + 67310.54841 // seconds
+	temp = math.Mod(temp*math.Pi/180/240, twoPi)
+	if temp < 0 {
+		temp += twoPi
+	}
+	return temp
+}
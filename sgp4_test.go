@@ -0,0 +1,85 @@
+package satfetch
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// verificationState is where a verification case has the satellite, in
+// km and km/s in the TEME frame.
+type verificationState struct {
+	tsince float64 // minutes from epoch
+	pos    [3]float64
+	vel    *[3]float64 // if given
+}
+
+// TestPropagateTEME checks SGP4 and SDP4 against Vallado's verification
+// cases (Vallado et al., "Revisiting Spacetrack Report #3", AIAA 2006-6753,
+// tcppver.out).
+func TestPropagateTEME(t *testing.T) {
+	tests := []struct {
+		name         string
+		line1, line2 string
+		toleranceKm  float64
+		states       []verificationState
+	}{
+		{
+			// Near Earth, propagated with SGP4.
+			name:        "00005",
+			line1:       "1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+			line2:       "2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667",
+			toleranceKm: 0.002,
+			states: []verificationState{
+				{0, [3]float64{7022.46529266, -1400.08296755, 0.03995155}, &[3]float64{1.893841015, 6.405893759, 4.534807250}},
+				{360, [3]float64{-7154.03120202, -3783.17682504, -3536.19412294}, &[3]float64{4.741887409, -4.151817765, -2.093935425}},
+				{720, [3]float64{-7134.59340119, 6531.68641334, 3260.27186483}, &[3]float64{-4.113793027, -2.911922039, -2.557327851}},
+				{1080, [3]float64{5568.53901181, 4492.06992591, 3863.87641983}, &[3]float64{-4.209106476, 5.159719888, 2.744852980}},
+				{1440, [3]float64{-938.55923943, -6268.18748831, -4294.02924751}, &[3]float64{7.536105209, -0.427127707, 0.989878080}},
+			},
+		},
+		{
+			// Deep space, with a period over 225 minutes, propagated with
+			// SDP4.
+			name:        "11801",
+			line1:       "1 11801U          80230.29629788  .01431103  00000-0  14311-1 0    13",
+			line2:       "2 11801  46.7916 230.4354 7318036  47.4722  10.4117  2.28537848    13",
+			toleranceKm: 0.05,
+			states: []verificationState{
+				{0, [3]float64{7473.37066650, 428.95261765, 5828.74786377}, nil},
+				{360, [3]float64{-3305.22537232, 32410.86328125, -24697.17675781}, nil},
+				{720, [3]float64{14271.28759766, 24110.46411133, -4725.76837158}, nil},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tle, err := ParseTLE(tt.line1, tt.line2)
+			if err != nil {
+				t.Fatalf("ParseTLE: %v", err)
+			}
+			for _, s := range tt.states {
+				at := tle.EpochTime().Add(time.Duration(s.tsince * float64(time.Minute)))
+				pos, vel, err := tle.PropagateTEME(at)
+				if err != nil {
+					t.Errorf("tsince %g: %v", s.tsince, err)
+					continue
+				}
+				if d := distance(pos, s.pos); d > tt.toleranceKm {
+					t.Errorf("tsince %g: position %.5f is %.4f km from %.5f", s.tsince, pos, d, s.pos)
+				}
+				if s.vel != nil {
+					if d := distance(vel, *s.vel); d > 1e-5 {
+						t.Errorf("tsince %g: velocity %.9f is %.2g km/s from %.9f", s.tsince, vel, d, *s.vel)
+					}
+				}
+			}
+		})
+	}
+}
+
+// distance returns how far apart a and b are.
+func distance(a [3]float64, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
@@ -0,0 +1,131 @@
+package satfetch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TLESource is somewhere the current element set for a satellite can be
+// fetched from.
+type TLESource interface {
+	// FetchTLE returns the latest two-line element set for noradId.
+	FetchTLE(noradId string) ([]byte, error)
+}
+
+// FetchTLE returns the latest element set Space Track has for noradId, from
+// the gp class unless the client's TLEClass is the deprecated tle.
+func (c *Client) FetchTLE(noradId string) ([]byte, error) {
+	if err := CheckNORADIDs(noradId); err != nil {
+		return nil, err
+	}
+	resp, err := c.Query(LatestTLEQueryPath(c.TLEClass, noradId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckTLEResponse(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FetchTLEsByDateRange returns the element sets Space Track has for noradID
+// with epochs between start and end, inclusive, oldest first. They come from
+// gp_history unless the client's TLEClass is the deprecated tle.
+func (c *Client) FetchTLEsByDateRange(noradID string, start time.Time, end time.Time) ([]byte, error) {
+	if err := CheckNORADIDs(noradID); err != nil {
+		return nil, err
+	}
+	class := "gp_history"
+	if c.TLEClass == "tle" {
+		class = "tle"
+	}
+	// Space Track's range operator is "--"; a comma would mean "or".
+	resp, err := c.Query(queryPath(class,
+		"NORAD_CAT_ID", noradID,
+		"EPOCH", spaceTrackTime(start)+"--"+spaceTrackTime(end),
+		"orderby", "EPOCH asc",
+		"format", "tle",
+		"metadata", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckTLEResponse(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DefaultCelestrakURL is CelesTrak's general perturbations query endpoint.
+const DefaultCelestrakURL = "https://celestrak.org/NORAD/elements/gp.php"
+
+// CelestrakSource fetches current element sets from CelesTrak, which needs
+// no account.
+type CelestrakSource struct {
+	URL        string
+	HTTPClient Doer
+}
+
+// NewCelestrakSource returns a CelestrakSource using DefaultCelestrakURL.
+func NewCelestrakSource() *CelestrakSource {
+	return &CelestrakSource{
+		URL:        DefaultCelestrakURL,
+		HTTPClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// FetchTLE returns CelesTrak's current element set for noradId.
+func (s *CelestrakSource) FetchTLE(noradId string) ([]byte, error) {
+	if err := CheckNORADIDs(noradId); err != nil {
+		return nil, err
+	}
+	// 2LE is the TLE format without the leading name line.
+	queryURL := s.URL + "?" + url.Values{
+		"CATNR":  {noradId},
+		"FORMAT": {"2LE"},
+	}.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, queryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", queryURL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response to %s: %v", queryURL, err)
+	}
+
+	// Unknown objects get a 200 with "No GP data found".
+	if err := CheckTLEResponse(body); err != nil {
+		return nil, fmt.Errorf("%s: %v", queryURL, err)
+	}
+	return body, nil
+}
+
+// CheckTLEResponse returns an error unless resp is empty or looks like
+// two-line element sets, so error pages aren't written out as TLEs.
+func CheckTLEResponse(resp []byte) error {
+	if len(resp) == 0 || strings.HasPrefix(string(resp), "1 ") {
+		return nil
+	}
+
+	snippet := string(resp)
+	if len(snippet) > 40 {
+		snippet = snippet[:40] + "..."
+	}
+	return fmt.Errorf("response does not look like TLEs: %q", snippet)
+}
@@ -0,0 +1,60 @@
+package satfetch
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientStats counts a Client's query requests, as returned by Stats.
+type ClientStats struct {
+	Requests      int64            // requests sent
+	Failures      map[string]int64 // failed requests by HTTP status code, "auth" or "error"
+	ResponseBytes int64            // response bytes received
+	LimiterWait   time.Duration    // total time spent waiting for the rate limits
+	LastSuccess   time.Time        // when the last request succeeded, or zero if none has
+}
+
+// Stats returns the counts of the client's query requests so far, its
+// accounts' included.
+func (c *Client) Stats() ClientStats {
+	s := c.stats
+	stats := ClientStats{
+		Requests:      s.requests.Load(),
+		Failures:      make(map[string]int64),
+		ResponseBytes: s.bytes.Load(),
+		LimiterWait:   time.Duration(s.limiterWait.Load()),
+	}
+	if last := s.lastSuccess.Load(); last != 0 {
+		stats.LastSuccess = time.Unix(last, 0)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for status, n := range s.failures {
+		stats.Failures[status] = n
+	}
+	return stats
+}
+
+// clientStats counts a Client's query requests, for metrics. It's safe for
+// concurrent use.
+type clientStats struct {
+	requests    atomic.Int64 // requests sent
+	bytes       atomic.Int64 // response bytes received
+	limiterWait atomic.Int64 // total time waiting for the rate limits, in nanoseconds
+	lastSuccess atomic.Int64 // when the last request succeeded, in Unix seconds
+
+	mu       sync.Mutex
+	failures map[string]int64 // failed requests by status code, "auth" or "error"
+}
+
+// fail counts a failed request.
+func (s *clientStats) fail(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures == nil {
+		s.failures = make(map[string]int64)
+	}
+	s.failures[status]++
+}
@@ -0,0 +1,493 @@
+package satfetch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLELineLength is the fixed width of each line of a two-line element set.
+const TLELineLength = 69
+
+// TLE represents a standard two-line element set. ElementSetNumber keeps
+// the JSON name it had as TLENumber, tleNumber, so that JSON files of
+// element sets written before the rename still read back.
+type TLE struct {
+	NORADID          uint64  `json:"noradid"`
+	Classification   string  `json:"classification"`
+	IntlDesignator   string  `json:"intlDesignator"`
+	Epoch            float64 `json:"epoch"`
+	MnMot1stDeriv    float64 `json:"meanMotion1stDeriv"` // divided by 2
+	MnMot2ndDeriv    float64 `json:"meanMotion2ndDeriv"` // divided by 6
+	BSTAR            float64 `json:"bstar"`
+	EphemerisType    int     `json:"ephemerisType"` // always 0 in distributed element sets
+	ElementSetNumber int     `json:"tleNumber"`     // counts up with each element set issued, modulo 10000
+	Line1Checksum    int     `json:"line1Checksum"` // modulo 10
+	Inclination      float32 `json:"inclination"`
+	RAAN             float32 `json:"raan"` // right ascension of asc node
+	Eccentricity     float32 `json:"eccentricity"`
+	ArgOfPerigee     float32 `json:"argumentOfPerigee"`
+	MeanAnomaly      float32 `json:"meanAnomaly"`
+	MeanMotion       float64 `json:"meanMotion"`
+	RevNumber        uint32  `json:"revolutionNumber"`
+	Line2Checksum    int     `json:"line2Checksum"`  // modulo 10
+	Name             string  `json:"name,omitempty"` // from a title line, less any "0 ", if it had one
+
+	// The text the element set was parsed from, if any, for VerifyChecksum.
+	line1, line2 string
+}
+
+// String summarizes the element set, one field per line.
+func (tle TLE) String() string {
+	return fmt.Sprintf("NORADID: %d\n"+
+		"Intl designator: %s\n"+
+		"Epoch: %014.8f\n"+
+		"Inclination: %.4f deg\n"+
+		"Eccentricity: %.7f\n"+
+		"Mean motion: %.8f rev/day\n",
+		tle.NORADID, tle.IntlDesignator, tle.Epoch,
+		tle.Inclination, tle.Eccentricity, tle.MeanMotion)
+}
+
+// TwoLine renders the element set in the canonical two-line format, with
+// freshly computed checksums, separated and terminated by newlines.
+func (tle TLE) TwoLine() string {
+	line1 := fmt.Sprintf("1 %05d%1.1s %-8.8s %014.8f %s %s %s %1d %4d",
+		tle.NORADID, tle.Classification, tle.IntlDesignator, tle.Epoch,
+		formatDecimalField(tle.MnMot1stDeriv), formatPackedField(tle.MnMot2ndDeriv),
+		formatPackedField(tle.BSTAR), tle.EphemerisType, tle.ElementSetNumber%10000)
+	line2 := fmt.Sprintf("2 %05d %8.4f %8.4f %07d %8.4f %8.4f %11.8f%5d",
+		tle.NORADID, tle.Inclination, tle.RAAN,
+		int(math.Round(float64(tle.Eccentricity)*1e7)),
+		tle.ArgOfPerigee, tle.MeanAnomaly, tle.MeanMotion, tle.RevNumber%100000)
+
+	return fmt.Sprintf("%s%d\n%s%d\n", line1, TLEChecksum(line1), line2, TLEChecksum(line2))
+}
+
+// EpochTime converts the packed YYDDD.DDDDDDDD epoch to a UTC time. Two-digit
+// years from 57 onward are taken to be in the 1900s, per the TLE convention.
+func (tle TLE) EpochTime() time.Time {
+	year := int(tle.Epoch / 1000)
+	day := tle.Epoch - float64(year*1000)
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+
+	// Day 1.0 is midnight on January 1st. Round to the microsecond to shed
+	// floating point noise; the field only carries about 1ms of precision.
+	micros := math.Round((day - 1) * 86400 * 1e6)
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Duration(micros) * time.Microsecond)
+}
+
+// PeriodMinutes returns the time the satellite takes to orbit once.
+func (tle TLE) PeriodMinutes() float64 {
+	return 1440 / tle.MeanMotion
+}
+
+// SemiMajorAxisKm returns the size of the orbit from its mean motion, by
+// Kepler's third law.
+func (tle TLE) SemiMajorAxisKm() float64 {
+	n := tle.MeanMotion * twoPi / 86400 // radians/second
+	return math.Cbrt(earthMu / (n * n))
+}
+
+// ApogeeKm returns the orbit's highest altitude above Earth's equatorial
+// radius.
+func (tle TLE) ApogeeKm() float64 {
+	return tle.SemiMajorAxisKm()*(1+float64(tle.Eccentricity)) - earthRadiusKm
+}
+
+// PerigeeKm returns the orbit's lowest altitude above Earth's equatorial
+// radius.
+func (tle TLE) PerigeeKm() float64 {
+	return tle.SemiMajorAxisKm()*(1-float64(tle.Eccentricity)) - earthRadiusKm
+}
+
+// Sanity returns what's implausible about the element set, if anything:
+// eccentricity outside [0, 1), inclination outside [0°, 180°], a mean motion
+// that isn't positive, a perigee below the Earth's surface, or an epoch more
+// than a day in the future. Such element sets parse but are garbage.
+func (tle TLE) Sanity() []string {
+	var warnings []string
+	if e := tle.Eccentricity; e < 0 || e >= 1 {
+		warnings = append(warnings, fmt.Sprintf("eccentricity %g is outside [0, 1)", e))
+	}
+	if tle.Inclination < 0 || tle.Inclination > 180 {
+		warnings = append(warnings, fmt.Sprintf("inclination %g° is outside [0°, 180°]", tle.Inclination))
+	}
+	if tle.MeanMotion <= 0 {
+		warnings = append(warnings, fmt.Sprintf("mean motion %g revs/day isn't positive", tle.MeanMotion))
+	} else if perigee := tle.PerigeeKm(); perigee < 0 {
+		warnings = append(warnings, fmt.Sprintf("perigee %.0f km is below the surface", perigee))
+	}
+	if ahead := time.Until(tle.EpochTime()); ahead > 24*time.Hour {
+		warnings = append(warnings, fmt.Sprintf("epoch %s is %s in the future",
+			tle.EpochTime().Format(time.RFC3339), ahead.Round(time.Hour)))
+	}
+	return warnings
+}
+
+// TLEChecksum computes the modulo-10 checksum of the first 68 columns of a
+// TLE line: the sum of its digits, with each minus sign counting as 1.
+func TLEChecksum(line string) int {
+	sum := 0
+	for i := 0; i < len(line) && i < TLELineLength-1; i++ {
+		switch c := line[i]; {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	return sum % 10
+}
+
+// formatDecimalField writes v with no leading zero, e.g. "-.00002182".
+func formatDecimalField(v float64) string {
+	sign := " "
+	if v < 0 {
+		sign = "-"
+	}
+	return sign + strings.TrimPrefix(fmt.Sprintf("%.8f", math.Abs(v)), "0")
+}
+
+// formatPackedField writes v in the assumed-decimal exponential notation
+// used for BSTAR and the second derivative, e.g. "-11606-4".
+func formatPackedField(v float64) string {
+	if v == 0 {
+		return " 00000-0"
+	}
+	sign := " "
+	if v < 0 {
+		sign = "-"
+	}
+	exp := int(math.Floor(math.Log10(math.Abs(v)))) + 1
+	mantissa := int(math.Round(math.Abs(v) / math.Pow(10, float64(exp)) * 1e5))
+	if mantissa >= 100000 {
+		mantissa /= 10
+		exp++
+	}
+	expSign := "+"
+	if exp < 0 {
+		expSign = "-"
+	}
+	return fmt.Sprintf("%s%05d%s%d", sign, mantissa, expSign, abs(exp))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ParseTLE parses the two lines of an element set into a TLE. Columns are
+// those of the fixed-width format documented by Space Track and CelesTrak.
+// A \r left on a line split from CRLF text is ignored.
+func ParseTLE(line1 string, line2 string) (TLE, error) {
+	var tle TLE
+
+	line1 = strings.TrimRight(line1, "\r")
+	line2 = strings.TrimRight(line2, "\r")
+	if len(line1) != TLELineLength {
+		return tle, fmt.Errorf("line 1 is %d characters long, want %d", len(line1), TLELineLength)
+	}
+	if len(line2) != TLELineLength {
+		return tle, fmt.Errorf("line 2 is %d characters long, want %d", len(line2), TLELineLength)
+	}
+	if line1[0] != '1' {
+		return tle, fmt.Errorf("line 1 begins with %q, want '1'", line1[0])
+	}
+	if line2[0] != '2' {
+		return tle, fmt.Errorf("line 2 begins with %q, want '2'", line2[0])
+	}
+
+	p := tleFieldParser{}
+
+	// Line 1
+	p.line = 1
+	tle.NORADID = p.uint("catalog number", line1[2:7])
+	tle.Classification = line1[7:8]
+	tle.IntlDesignator = strings.TrimSpace(line1[9:17])
+	tle.Epoch = p.float("epoch", line1[18:32])
+	tle.MnMot1stDeriv = p.float("mean motion 1st derivative", line1[33:43])
+	tle.MnMot2ndDeriv = p.packed("mean motion 2nd derivative", line1[44:52])
+	tle.BSTAR = p.packed("BSTAR", line1[53:61])
+	tle.EphemerisType = p.int("ephemeris type", line1[62:63])
+	tle.ElementSetNumber = p.int("element set number", line1[64:68])
+	tle.Line1Checksum = p.int("checksum", line1[68:69])
+
+	// Line 2
+	p.line = 2
+	line2NORADID := p.uint("catalog number", line2[2:7])
+	tle.Inclination = float32(p.float("inclination", line2[8:16]))
+	tle.RAAN = float32(p.float("right ascension", line2[17:25]))
+	tle.Eccentricity = float32(p.float("eccentricity", "."+line2[26:33]))
+	tle.ArgOfPerigee = float32(p.float("argument of perigee", line2[34:42]))
+	tle.MeanAnomaly = float32(p.float("mean anomaly", line2[43:51]))
+	tle.MeanMotion = p.float("mean motion", line2[52:63])
+	tle.RevNumber = uint32(p.uint("revolution number", line2[63:68]))
+	tle.Line2Checksum = p.int("checksum", line2[68:69])
+
+	tle.line1, tle.line2 = line1, line2
+	if p.err == nil && line2NORADID != tle.NORADID {
+		return tle, fmt.Errorf("line 1 is for %d but line 2 is for %d", tle.NORADID, line2NORADID)
+	}
+	return tle, p.err
+}
+
+// ParseTLEStrict is like ParseTLE but also rejects lines whose checksum
+// digit doesn't match their contents.
+func ParseTLEStrict(line1 string, line2 string) (TLE, error) {
+	tle, err := ParseTLE(line1, line2)
+	if err != nil {
+		return tle, err
+	}
+	return tle, tle.VerifyChecksum()
+}
+
+// VerifyChecksum checks the element set's checksum digits against its text.
+// For a TLE that wasn't parsed from text, the text is that of TwoLine.
+func (tle TLE) VerifyChecksum() error {
+	line1, line2 := tle.line1, tle.line2
+	if line1 == "" || line2 == "" {
+		lines := strings.SplitN(tle.TwoLine(), "\n", 3)
+		line1, line2 = lines[0], lines[1]
+	}
+
+	if sum := TLEChecksum(line1); sum != tle.Line1Checksum {
+		return fmt.Errorf("line 1 checksum is %d, want %d", tle.Line1Checksum, sum)
+	}
+	if sum := TLEChecksum(line2); sum != tle.Line2Checksum {
+		return fmt.Errorf("line 2 checksum is %d, want %d", tle.Line2Checksum, sum)
+	}
+	return nil
+}
+
+// tleFieldParser extracts numeric fields from TLE columns, remembering the
+// first error so ParseTLE can read every field before checking.
+type tleFieldParser struct {
+	line int
+	err  error
+}
+
+func (p *tleFieldParser) fail(name string, s string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("line %d: bad %s %q: %v", p.line, name, s, err)
+	}
+}
+
+func (p *tleFieldParser) float(name string, field string) float64 {
+	s := strings.TrimSpace(field)
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		p.fail(name, s, err)
+	}
+	return v
+}
+
+func (p *tleFieldParser) int(name string, field string) int {
+	s := strings.TrimSpace(field)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		p.fail(name, s, err)
+	}
+	return v
+}
+
+func (p *tleFieldParser) uint(name string, field string) uint64 {
+	s := strings.TrimSpace(field)
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		p.fail(name, s, err)
+	}
+	return v
+}
+
+// packed parses a field in the assumed-decimal exponential notation.
+func (p *tleFieldParser) packed(name string, field string) float64 {
+	v, err := parsePackedFloat(field)
+	if err != nil {
+		p.fail(name, strings.TrimSpace(field), err)
+	}
+	return v
+}
+
+// LatestTLE returns the element set with the latest epoch, or false if there
+// are none.
+func LatestTLE(tles []TLE) (TLE, bool) {
+	if len(tles) == 0 {
+		return TLE{}, false
+	}
+	latest := tles[0]
+	for _, tle := range tles[1:] {
+		if tle.EpochTime().After(latest.EpochTime()) {
+			latest = tle
+		}
+	}
+	return latest, true
+}
+
+// AgeAt returns how long before t the element set's epoch is, negative if
+// it's after t.
+func (tle TLE) AgeAt(t time.Time) time.Duration {
+	return t.Sub(tle.EpochTime())
+}
+
+// afterEpochPenalty is how many times further away BestTLE counts element
+// sets with epochs after the target time, as they're fitted to observations
+// that may follow a manoeuvre since.
+const afterEpochPenalty = 2
+
+// BestTLE returns the element set to propagate to t: the one with the epoch
+// closest to t, counting those after it as afterEpochPenalty times as far,
+// as propagation error grows with time from epoch either way. It returns
+// false if there are none.
+func BestTLE(tles []TLE, t time.Time) (TLE, bool) {
+	if len(tles) == 0 {
+		return TLE{}, false
+	}
+	distance := func(tle TLE) time.Duration {
+		age := tle.AgeAt(t)
+		if age < 0 {
+			return -age * afterEpochPenalty
+		}
+		return age
+	}
+
+	best := tles[0]
+	for _, tle := range tles[1:] {
+		if distance(tle) < distance(best) {
+			best = tle
+		}
+	}
+	return best, true
+}
+
+// IterateTLEs reads element sets from r one at a time, calling fn with
+// each, so files of any size can be processed in constant memory. The
+// element sets may be in two line form or three line form, with a title
+// line before each, and blank lines are ignored. It stops at the first
+// error, including one returned by fn.
+func IterateTLEs(r io.Reader, fn func(TLE) error) error {
+	scanner := bufio.NewScanner(r)
+	var line1, title string
+	line1At := 0
+	titled := false
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			continue
+		case line1 != "":
+			tle, err := ParseTLE(line1, line)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line1At, err)
+			}
+			tle.Name = title
+			if err := fn(tle); err != nil {
+				return err
+			}
+			line1, title = "", ""
+			titled = false
+		case strings.HasPrefix(line, "1 "):
+			line1, line1At = line, n
+		case !titled:
+			// A title line, which must be followed by line 1.
+			title = strings.TrimSpace(strings.TrimPrefix(line, "0 "))
+			titled = true
+		default:
+			return fmt.Errorf("line %d: want line 1 of an element set, got %q", n, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if line1 != "" {
+		return fmt.Errorf("line %d: element set has no line 2", line1At)
+	}
+	if titled {
+		return errors.New("title line at the end has no element set")
+	}
+	return nil
+}
+
+// ParseTLEText parses all the element sets in data, as IterateTLEs reads
+// them.
+func ParseTLEText(data []byte) ([]TLE, error) {
+	var tles []TLE
+	err := IterateTLEs(bytes.NewReader(data), func(tle TLE) error {
+		tles = append(tles, tle)
+		return nil
+	})
+	return tles, err
+}
+
+// VerifyTLEText checks that data holds only whole element sets whose
+// checksums match their text.
+func VerifyTLEText(data []byte) error {
+	return IterateTLEs(bytes.NewReader(data), func(tle TLE) error {
+		if err := tle.VerifyChecksum(); err != nil {
+			return fmt.Errorf("NORAD ID %d: %v", tle.NORADID, err)
+		}
+		return nil
+	})
+}
+
+// Text returns the element set as the two lines it was parsed from, or
+// rebuilt by TwoLine if it wasn't parsed.
+func (tle TLE) Text() string {
+	if tle.line1 == "" || tle.line2 == "" {
+		return tle.TwoLine()
+	}
+	return tle.line1 + "\n" + tle.line2 + "\n"
+}
+
+// parsePackedFloat decodes a TLE field written with an assumed leading
+// decimal point and a trailing signed exponent, as used for BSTAR and the
+// second derivative of mean motion: "-11606-4" is -0.11606e-4, and
+// " 00000-0" or "+00000+0" is zero.
+func parsePackedFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 3 {
+		return 0, fmt.Errorf("packed field %q is too short", s)
+	}
+
+	mantissa, exponent := s[:len(s)-2], s[len(s)-2:]
+	if exponent[0] != '-' && exponent[0] != '+' {
+		return 0, fmt.Errorf("packed field %q has no exponent sign", s)
+	}
+
+	sign := ""
+	switch mantissa[0] {
+	case '-':
+		sign = "-"
+		fallthrough
+	case '+':
+		mantissa = mantissa[1:]
+	}
+	if mantissa == "" {
+		return 0, fmt.Errorf("packed field %q has no mantissa", s)
+	}
+	for i := 0; i < len(mantissa); i++ {
+		if mantissa[i] < '0' || mantissa[i] > '9' {
+			return 0, fmt.Errorf("packed field %q has a non-digit mantissa", s)
+		}
+	}
+
+	return strconv.ParseFloat(sign+"."+mantissa+"e"+exponent, 64)
+}
@@ -0,0 +1,365 @@
+package satfetch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Real element sets, for the ISS and for NAVSTAR 54 (USA 175), a GPS
+// satellite.
+const (
+	issLine1 = "1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927"
+	issLine2 = "2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537"
+	gpsLine1 = "1 28129U 03058A   06175.57071136 -.00000104  00000-0  10000-3 0   459"
+	gpsLine2 = "2 28129  54.7298 324.8098 0048506 266.2640  93.1663  2.00562768 18443"
+)
+
+func TestParseTLE(t *testing.T) {
+	tests := []struct {
+		name         string
+		line1, line2 string
+		want         TLE
+	}{
+		{
+			name:  "ISS",
+			line1: issLine1,
+			line2: issLine2,
+			want: TLE{
+				NORADID:          25544,
+				Classification:   "U",
+				IntlDesignator:   "98067A",
+				Epoch:            8264.51782528,
+				MnMot1stDeriv:    -.00002182,
+				MnMot2ndDeriv:    0,
+				BSTAR:            -.11606e-4,
+				EphemerisType:    0,
+				ElementSetNumber: 292,
+				Line1Checksum:    7,
+				Inclination:      51.6416,
+				RAAN:             247.4627,
+				Eccentricity:     .0006703,
+				ArgOfPerigee:     130.5360,
+				MeanAnomaly:      325.0288,
+				MeanMotion:       15.72125391,
+				RevNumber:        56353,
+				Line2Checksum:    7,
+			},
+		},
+		{
+			name:  "GPS",
+			line1: gpsLine1,
+			line2: gpsLine2,
+			want: TLE{
+				NORADID:          28129,
+				Classification:   "U",
+				IntlDesignator:   "03058A",
+				Epoch:            6175.57071136,
+				MnMot1stDeriv:    -.00000104,
+				MnMot2ndDeriv:    0,
+				BSTAR:            .1e-3,
+				EphemerisType:    0,
+				ElementSetNumber: 45,
+				Line1Checksum:    9,
+				Inclination:      54.7298,
+				RAAN:             324.8098,
+				Eccentricity:     .0048506,
+				ArgOfPerigee:     266.2640,
+				MeanAnomaly:      93.1663,
+				MeanMotion:       2.00562768,
+				RevNumber:        1844,
+				Line2Checksum:    3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTLE(tt.line1, tt.line2)
+			if err != nil {
+				t.Fatalf("ParseTLE: %v", err)
+			}
+			tt.want.line1, tt.want.line2 = tt.line1, tt.line2
+			if got != tt.want {
+				t.Errorf("ParseTLE =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if err := got.VerifyChecksum(); err != nil {
+				t.Errorf("VerifyChecksum: %v", err)
+			}
+			if text := got.TwoLine(); text != tt.line1+"\n"+tt.line2+"\n" {
+				t.Errorf("TwoLine =\n%s\nwant\n%s\n%s", text, tt.line1, tt.line2)
+			}
+		})
+	}
+}
+
+func TestParseTLEErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		line1, line2 string
+		strict       bool
+		want         string // in the error
+	}{
+		{"short line 1", issLine1[:68], issLine2, false, "line 1 is 68 characters long"},
+		{"long line 2", issLine1, issLine2 + " ", false, "line 2 is 70 characters long"},
+		{"swapped lines", issLine2, issLine1, false, "line 1 begins with '2'"},
+		{"bad epoch", issLine1[:18] + "08264.5178252x" + issLine1[32:], issLine2, false, `line 1: bad epoch "08264.5178252x"`},
+		{"bad inclination", issLine1, issLine2[:8] + " 51.64x6" + issLine2[16:], false, `line 2: bad inclination "51.64x6"`},
+		{"bad BSTAR", issLine1[:53] + "-11606x4" + issLine1[61:], issLine2, false, `line 1: bad BSTAR "-11606x4"`},
+		{"mismatched catalog numbers", issLine1, gpsLine2, false, "line 1 is for 25544 but line 2 is for 28129"},
+		{"bad checksum", issLine1[:68] + "8", issLine2, true, "line 1 checksum is 8, want 7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParseTLE
+			if tt.strict {
+				parse = ParseTLEStrict
+			}
+			_, err := parse(tt.line1, tt.line2)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLEChecksum(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{issLine1, 7},
+		{issLine2, 7},
+		{gpsLine1, 9},
+		{gpsLine2, 3},
+		// Each minus sign counts as 1; letters, spaces, periods and plus
+		// signs count for nothing.
+		{"-", 1},
+		{"1-2", 4},
+		{"-.00002182", 4},
+		{"U A+. 5", 5},
+		// Only the 68 columns before the checksum digit count.
+		{issLine1[:68] + "9", 7},
+	}
+	for _, tt := range tests {
+		if got := TLEChecksum(tt.line); got != tt.want {
+			t.Errorf("TLEChecksum(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	tle, err := ParseTLE(issLine1, issLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+	if err := tle.VerifyChecksum(); err != nil {
+		t.Errorf("VerifyChecksum: %v", err)
+	}
+
+	line1 := tle
+	line1.Line1Checksum = 8
+	if err := line1.VerifyChecksum(); err == nil || err.Error() != "line 1 checksum is 8, want 7" {
+		t.Errorf("VerifyChecksum of a wrong line 1 checksum: error = %v", err)
+	}
+	line2 := tle
+	line2.Line2Checksum = 0
+	if err := line2.VerifyChecksum(); err == nil || err.Error() != "line 2 checksum is 0, want 7" {
+		t.Errorf("VerifyChecksum of a wrong line 2 checksum: error = %v", err)
+	}
+
+	// A changed element no longer matches the checksum of its text.
+	changed, err := ParseTLE(issLine1, issLine2[:8]+" 51.6417"+issLine2[16:])
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+	if err := changed.VerifyChecksum(); err == nil {
+		t.Error("VerifyChecksum of a changed inclination succeeded")
+	}
+}
+
+func TestEpochTime(t *testing.T) {
+	tests := []struct {
+		epoch float64
+		want  time.Time
+	}{
+		{21275.78495062, time.Date(2021, time.October, 2, 18, 50, 19, 733568000, time.UTC)},
+		{8264.51782528, time.Date(2008, time.September, 20, 12, 25, 40, 104192000, time.UTC)},
+		// Two-digit years pivot at 57, the year of the first satellite.
+		{56001, time.Date(2056, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{57001, time.Date(1957, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{99365.5, time.Date(1999, time.December, 31, 12, 0, 0, 0, time.UTC)},
+		{1, time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// Leap years have a day 366.
+		{20366.5, time.Date(2020, time.December, 31, 12, 0, 0, 0, time.UTC)},
+		{24060, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		tle := TLE{Epoch: tt.epoch}
+		got := tle.EpochTime()
+		if !got.Equal(tt.want) {
+			t.Errorf("EpochTime of %014.8f = %s, want %s", tt.epoch, got, tt.want)
+		}
+
+		// And back, through the epoch field TwoLine writes.
+		tle.NORADID, tle.MeanMotion = 25544, 15.5
+		lines := strings.Split(tle.TwoLine(), "\n")
+		if field := lines[0][18:32]; field != fmt.Sprintf("%014.8f", tt.epoch) {
+			t.Errorf("TwoLine epoch field = %q, want %014.8f", field, tt.epoch)
+		}
+		if parsed, err := ParseTLE(lines[0], lines[1]); err != nil {
+			t.Errorf("ParseTLE: %v", err)
+		} else if !parsed.EpochTime().Equal(tt.want) {
+			t.Errorf("EpochTime of %014.8f after a round trip = %s, want %s", tt.epoch, parsed.EpochTime(), tt.want)
+		}
+	}
+}
+
+func TestParsePackedFloat(t *testing.T) {
+	tests := []struct {
+		field   string
+		want    float64
+		wantErr string // in the error, if any
+	}{
+		{"-11606-4", -.11606e-4, ""},
+		{"+11606-4", .11606e-4, ""},
+		{" 11606-4", .11606e-4, ""},
+		{" 10000-3", .1e-3, ""},
+		{"-12345-3", -.12345e-3, ""},
+		{"+12345+1", 1.2345, ""},
+		{" 00000-0", 0, ""},
+		{"+00000+0", 0, ""},
+		{"", 0, "too short"},
+		{"-4", 0, "too short"},
+		{" 11606 4", 0, "no exponent sign"},
+		{" 11606x4", 0, "no exponent sign"},
+		{"--4", 0, "no mantissa"},
+		{"-1x606-4", 0, "non-digit mantissa"},
+		{"+-1606-4", 0, "non-digit mantissa"},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePackedFloat(tt.field)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("parsePackedFloat(%q): %v", tt.field, err)
+		case tt.wantErr == "" && got != tt.want:
+			t.Errorf("parsePackedFloat(%q) = %g, want %g", tt.field, got, tt.want)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("parsePackedFloat(%q) error = %v, want one containing %q", tt.field, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIterateTLEs(t *testing.T) {
+	iss := issLine1 + "\n" + issLine2 + "\n"
+	gps := gpsLine1 + "\n" + gpsLine2 + "\n"
+
+	tests := []struct {
+		name    string
+		text    string
+		want    []uint64 // NORAD IDs, in order
+		wantErr string   // in the error, if any
+	}{
+		{"empty", "", nil, ""},
+		{"two line", iss + gps, []uint64{25544, 28129}, ""},
+		{"three line", "ISS (ZARYA)\n" + iss + "NAVSTAR 54 (USA 175)\n" + gps, []uint64{25544, 28129}, ""},
+		{"mixed", iss + "NAVSTAR 54 (USA 175)\n" + gps + iss, []uint64{25544, 28129, 25544}, ""},
+		{"blank lines", "\n" + issLine1 + "\n\n  \n" + issLine2 + "\n\nNAVSTAR 54 (USA 175)\n\n" + gps + "\n\n", []uint64{25544, 28129}, ""},
+		{"no final newline", iss + gpsLine1 + "\n" + gpsLine2, []uint64{25544, 28129}, ""},
+		{"dangling line 1", iss + "\n" + gpsLine1 + "\n", []uint64{25544}, "line 4: element set has no line 2"},
+		{"line 1 then line 1", issLine1 + "\n" + gpsLine1 + "\n", nil, "line 1: line 2 begins with '1'"},
+		{"two titles", "ISS (ZARYA)\nZARYA\n" + iss, nil, `line 2: want line 1 of an element set, got "ZARYA"`},
+		{"title at the end", iss + "ISS (ZARYA)\n", []uint64{25544}, "title line at the end has no element set"},
+		{"bad element set", "ISS (ZARYA)\n" + issLine1 + "\n" + issLine2[:68] + "\n", nil, "line 2: line 2 is 68 characters long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uint64
+			err := IterateTLEs(strings.NewReader(tt.text), func(tle TLE) error {
+				got = append(got, tle.NORADID)
+				return nil
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("IterateTLEs: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("IterateTLEs error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("IterateTLEs called fn with %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIterateTLEsStops(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := IterateTLEs(strings.NewReader(strings.Repeat(issLine1+"\n"+issLine2+"\n", 3)), func(TLE) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("IterateTLEs = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func TestOrbitISS(t *testing.T) {
+	tle, err := ParseTLE(issLine1, issLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+
+	// From the mean motion of 15.72125391 revs/day and eccentricity of
+	// 0.0006703, with WGS-72's μ and equatorial radius.
+	tests := []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"period, minutes", tle.PeriodMinutes(), 91.5957, 0.001},
+		{"semi-major axis, km", tle.SemiMajorAxisKm(), 6730.96, 0.1},
+		{"apogee, km", tle.ApogeeKm(), 357.34, 0.1},
+		{"perigee, km", tle.PerigeeKm(), 348.32, 0.1},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > tt.tolerance {
+			t.Errorf("%s = %.4f, want %.4f ± %g", tt.name, tt.got, tt.want, tt.tolerance)
+		}
+	}
+}
+
+func TestParseTLETextCRLF(t *testing.T) {
+	lf := "ISS (ZARYA)\n" + issLine1 + "\n" + issLine2 + "\n\n" +
+		"0 NAVSTAR 54 (USA 175)\n" + gpsLine1 + "\n" + gpsLine2 + "\n"
+	crlf := strings.ReplaceAll(lf, "\n", "\r\n")
+
+	want, err := ParseTLEText([]byte(lf))
+	if err != nil {
+		t.Fatalf("ParseTLEText of LF text: %v", err)
+	}
+	got, err := ParseTLEText([]byte(crlf))
+	if err != nil {
+		t.Fatalf("ParseTLEText of CRLF text: %v", err)
+	}
+	if len(got) != 2 || len(got) != len(want) {
+		t.Fatalf("ParseTLEText of CRLF text returned %d element sets, want 2", len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("element set %d from CRLF text =\n%+v\nwant\n%+v", i, got[i], want[i])
+		}
+	}
+	if got[0].Name != "ISS (ZARYA)" || got[1].Name != "NAVSTAR 54 (USA 175)" {
+		t.Errorf("names = %q, %q", got[0].Name, got[1].Name)
+	}
+	if err := VerifyTLEText([]byte(crlf)); err != nil {
+		t.Errorf("VerifyTLEText of CRLF text: %v", err)
+	}
+}